@@ -8,6 +8,7 @@ require (
 	github.com/hashicorp/terraform-plugin-testing v1.13.3
 	go.austindrenski.io/gotter v0.0.0-20250908195653-93724ac9ec5b
 	go.opentelemetry.io/otel v1.38.0
+	go.yaml.in/yaml/v3 v3.0.4
 )
 
 require (
@@ -0,0 +1,206 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math/big"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// unwrap converts a Terraform value into the native Go values expected by
+// template authors: objects and maps become map[string]any, lists, sets and
+// tuples become []any, and primitives become their Go equivalents. Values
+// that are not yet known are represented by unknown.
+func unwrap(v attr.Value) any {
+	if v == nil || v.IsNull() {
+		return nil
+	} else if v.IsUnknown() {
+		return unknown{}
+	}
+
+	switch v := v.(type) {
+	case types.Bool:
+		return v.ValueBool()
+	case types.Dynamic:
+		return unwrap(v.UnderlyingValue())
+	case types.List:
+		return unwrapElements(v.Elements())
+	case types.Map:
+		return unwrapAttributes(v.Elements())
+	case types.Number:
+		return number(v.ValueBigFloat())
+	case types.Object:
+		return unwrapAttributes(v.Attributes())
+	case types.Set:
+		s := unwrapElements(v.Elements())
+		slices.SortStableFunc(s, compare)
+		return s
+	case types.String:
+		return v.ValueString()
+	case types.Tuple:
+		return unwrapElements(v.Elements())
+	default:
+		return nil
+	}
+}
+
+func unwrapAttributes(attributes map[string]attr.Value) map[string]any {
+	m := make(map[string]any, len(attributes))
+
+	for k, v := range attributes {
+		m[k] = unwrap(v)
+	}
+
+	return m
+}
+
+func unwrapElements(elements []attr.Value) []any {
+	s := make([]any, len(elements))
+
+	for i, v := range elements {
+		s[i] = unwrap(v)
+	}
+
+	return s
+}
+
+// wrap converts a native Go value produced by a template into a Terraform
+// value, the inverse of unwrap: maps with string keys become objects, slices
+// become tuples, and numbers of any Go type become Terraform numbers. Values
+// that are not yet known become unknown dynamic values.
+func wrap(v any) (attr.Value, error) {
+	switch v := v.(type) {
+	case nil:
+		return types.DynamicNull(), nil
+	case unknown:
+		return types.DynamicUnknown(), nil
+	case bool:
+		return types.BoolValue(v), nil
+	case string:
+		return types.StringValue(v), nil
+	case float32, float64:
+		// Floats are converted through their shortest decimal representation,
+		// so that 0.1 remains 0.1 rather than its binary approximation.
+		f, _, err := big.ParseFloat(fmt.Sprint(v), 10, precision, big.ToNearestEven)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert %v to a number", v)
+		}
+
+		return types.NumberValue(f), nil
+	}
+
+	if f, ok := numeric(v); ok {
+		return types.NumberValue(f), nil
+	}
+
+	switch r := reflect.ValueOf(v); r.Kind() {
+	case reflect.Array, reflect.Slice:
+		elementTypes := make([]attr.Type, r.Len())
+		elements := make([]attr.Value, r.Len())
+
+		for i := range r.Len() {
+			e, err := wrap(r.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+
+			elementTypes[i] = e.Type(context.Background())
+			elements[i] = e
+		}
+
+		return types.TupleValueMust(elementTypes, elements), nil
+	case reflect.Map:
+		if r.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("cannot convert %T to a Terraform value", v)
+		}
+
+		attributeTypes := make(map[string]attr.Type, r.Len())
+		attributes := make(map[string]attr.Value, r.Len())
+
+		for iter := r.MapRange(); iter.Next(); {
+			e, err := wrap(iter.Value().Interface())
+			if err != nil {
+				return nil, err
+			}
+
+			attributeTypes[iter.Key().String()] = e.Type(context.Background())
+			attributes[iter.Key().String()] = e
+		}
+
+		return types.ObjectValueMust(attributeTypes, attributes), nil
+	default:
+		return nil, fmt.Errorf("cannot convert %T to a Terraform value", v)
+	}
+}
+
+// compare orders unwrapped values so that set elements are rendered in a
+// deterministic order regardless of the order Terraform sent them in.
+func compare(a any, b any) int {
+	if c := kind(a) - kind(b); c != 0 {
+		return c
+	}
+
+	if c, ok := compareNumbers(a, b); ok {
+		return c
+	}
+
+	switch a := a.(type) {
+	case bool:
+		if a == b.(bool) {
+			return 0
+		} else if a {
+			return 1
+		} else {
+			return -1
+		}
+	case string:
+		return strings.Compare(a, b.(string))
+	case []any:
+		return slices.CompareFunc(a, b.([]any), compare)
+	case map[string]any:
+		m := b.(map[string]any)
+
+		ak := slices.Sorted(maps.Keys(a))
+		bk := slices.Sorted(maps.Keys(m))
+
+		if c := slices.Compare(ak, bk); c != 0 {
+			return c
+		}
+
+		for _, k := range ak {
+			if c := compare(a[k], m[k]); c != 0 {
+				return c
+			}
+		}
+
+		return 0
+	default:
+		return 0
+	}
+}
+
+// kind ranks the unwrapped value types relative to each other.
+func kind(v any) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case int64, float64, *big.Int, *big.Float:
+		return 2
+	case string:
+		return 3
+	case []any:
+		return 4
+	case map[string]any:
+		return 5
+	default:
+		return 6
+	}
+}
@@ -0,0 +1,417 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// decodeError reports rendered output that failed to decode, where column is
+// zero when the decoder only reports the line. The source is the position in
+// the template of the text or action that wrote the output, where known.
+type decodeError struct {
+	column  int
+	format  string
+	line    int
+	message string
+	source  *templateLocation
+	text    string
+}
+
+// templateLocation is a position in the text of a template.
+type templateLocation struct {
+	column int
+	line   int
+	name   string
+	text   string
+}
+
+// Error describes the failure along with the surrounding lines of the
+// rendered output, pointing at the column where it is known, and the
+// surrounding lines of the template that wrote it.
+func (e *decodeError) Error() string {
+	where := fmt.Sprintf("line %d", e.line)
+	if e.column > 0 {
+		where += fmt.Sprintf(", column %d", e.column)
+	}
+
+	s := fmt.Sprintf("rendered output is not valid %s at %s: %s\n\n%s", e.format, where, e.message, excerpt(e.text, e.line, e.column))
+
+	if e.source != nil {
+		s += fmt.Sprintf("\n\nwritten by %s at line %d, column %d:\n\n%s", e.source.name, e.source.line, e.source.column, excerpt(e.source.text, e.source.line, e.source.column))
+	}
+
+	return s
+}
+
+// trace sets the source of the error to the text or action of the template
+// that wrote the output at the position of the error, where the decoder only
+// reporting the line is taken to mean the first character on the line.
+func (e *decodeError) trace(r *tracer) {
+	lines := strings.SplitAfter(e.text, "\n")
+	if e.line < 1 || e.line > len(lines) {
+		return
+	}
+
+	var offset int
+	for _, l := range lines[:e.line-1] {
+		offset += len(l)
+	}
+
+	line := lines[e.line-1]
+	n := len(line) - len(strings.TrimLeft(line, " \t"))
+
+	if e.column > 0 {
+		n = len(line)
+
+		column := e.column
+		for i := range line {
+			if column--; column == 0 {
+				n = i
+				break
+			}
+		}
+	}
+
+	e.source, _ = r.locate(offset + n)
+}
+
+// excerptWidth is the number of characters shown either side of the column
+// for lines too long to show in full, such as minified JSON.
+const excerptWidth = 40
+
+// excerpt returns the lines of the text surrounding the line, numbered, with
+// a caret under the column if it is not zero.
+func excerpt(text string, line int, column int) string {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+
+	first := max(line-2, 1)
+	last := min(line+2, len(lines))
+	width := len(strconv.Itoa(last))
+
+	var b strings.Builder
+
+	for i := first; i <= last; i++ {
+		runes := []rune(strings.TrimSuffix(lines[i-1], "\r"))
+		caret := min(column-1, len(runes))
+		s := runes
+
+		if len(runes) > 3*excerptWidth {
+			var start int
+			if i == line {
+				start = max(min(caret-excerptWidth, len(runes)-2*excerptWidth), 0)
+			}
+
+			end := start + 2*excerptWidth
+			s = slices.Clone(runes[start:end])
+			caret -= start
+
+			if start > 0 {
+				s = append([]rune("..."), s...)
+				caret += 3
+			}
+
+			if end < len(runes) {
+				s = append(s, []rune("...")...)
+			}
+		}
+
+		fmt.Fprintf(&b, "%*d | %s\n", width, i, string(s))
+
+		if i == line && column > 0 {
+			// Tabs are kept so that the caret lines up with the text.
+			pad := slices.Clone(s[:caret])
+			for j, r := range pad {
+				if r != '\t' {
+					pad[j] = ' '
+				}
+			}
+
+			fmt.Fprintf(&b, "%*s | %s^\n", width, "", string(pad))
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// position returns the line and column of the byte offset in the text, both
+// starting at one, where columns count characters.
+func position(text string, offset int) (int, int) {
+	offset = max(min(offset, len(text)), 0)
+
+	before := text[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := utf8.RuneCountInString(before[strings.LastIndexByte(before, '\n')+1:]) + 1
+
+	return line, column
+}
+
+// parseJSON decodes rendered JSON into the same representation as unwrap,
+// reporting the position of any syntax error.
+func parseJSON(text string) (any, error) {
+	d := json.NewDecoder(strings.NewReader(text))
+	d.UseNumber()
+
+	fail := func(offset int, message string) error {
+		line, column := position(text, offset)
+		return &decodeError{column: column, format: "JSON", line: line, message: message, text: text}
+	}
+
+	var v any
+	var syntax *json.SyntaxError
+
+	if err := d.Decode(&v); errors.As(err, &syntax) {
+		// The offset follows the character that could not be decoded.
+		return nil, fail(int(syntax.Offset)-1, syntax.Error())
+	} else if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fail(len(strings.TrimRight(text, " \t\r\n")), "unexpected end of JSON input")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if offset := int(d.InputOffset()); strings.TrimSpace(text[offset:]) != "" {
+		offset += len(text[offset:]) - len(strings.TrimLeft(text[offset:], " \t\r\n"))
+		r, _ := utf8.DecodeRuneInString(text[offset:])
+
+		return nil, fail(offset, fmt.Sprintf("invalid character %q after top-level value", r))
+	}
+
+	return fromJSON(v)
+}
+
+// yamlError matches the errors that the YAML decoder reports for malformed
+// documents, capturing the line and the message.
+var yamlError = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// yamlParserErrors lists the problems that the YAML decoder reports against
+// the line of the enclosing collection, counting from zero, rather than the
+// line of the problem itself. The line is omitted when it would be zero.
+var yamlParserErrors = []string{
+	"did not find expected '-' indicator",
+	"did not find expected ',' or ']'",
+	"did not find expected ',' or '}'",
+	"did not find expected <document start>",
+	"did not find expected <stream-start>",
+	"did not find expected key",
+	"did not find expected node content",
+	"found duplicate %TAG directive",
+	"found duplicate %YAML directive",
+	"found incompatible YAML document",
+	"found undefined tag handle",
+}
+
+// parseYAML decodes a rendered YAML document into the same representation as
+// unwrap, in the style of yamldecode, reporting the position of any error.
+func parseYAML(text string) (any, error) {
+	d := yaml.NewDecoder(strings.NewReader(text))
+
+	var root yaml.Node
+
+	if err := d.Decode(&root); errors.Is(err, io.EOF) {
+		return nil, nil
+	} else if err != nil {
+		line, message := 1, strings.TrimPrefix(err.Error(), "yaml: ")
+
+		if match := yamlError.FindStringSubmatch(err.Error()); match != nil {
+			line, _ = strconv.Atoi(match[1])
+			message = match[2]
+
+			if slices.Contains(yamlParserErrors, message) {
+				line++
+			}
+		}
+
+		return nil, &decodeError{format: "YAML", line: line, message: message, text: text}
+	}
+
+	var next yaml.Node
+
+	if err := d.Decode(&next); err == nil {
+		return nil, &decodeError{column: next.Column, format: "YAML", line: next.Line, message: "expected a single document", text: text}
+	} else if !errors.Is(err, io.EOF) {
+		return nil, &decodeError{format: "YAML", line: root.Line, message: "expected a single document", text: text}
+	}
+
+	v, err := fromYAML(&root)
+
+	var e *decodeError
+	if errors.As(err, &e) {
+		e.text = text
+	}
+
+	return v, err
+}
+
+func fromYAML(node *yaml.Node) (any, error) {
+	fail := func(node *yaml.Node, format string, args ...any) error {
+		return &decodeError{column: node.Column, format: "YAML", line: node.Line, message: fmt.Sprintf(format, args...)}
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+
+		return fromYAML(node.Content[0])
+	case yaml.AliasNode:
+		return fromYAML(node.Alias)
+	case yaml.SequenceNode:
+		s := make([]any, len(node.Content))
+
+		for i, n := range node.Content {
+			var err error
+			if s[i], err = fromYAML(n); err != nil {
+				return nil, err
+			}
+		}
+
+		return s, nil
+	case yaml.MappingNode:
+		m := map[string]any{}
+		set := map[string]bool{}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+
+			// Merge keys copy the entries of other mappings, which the mapping's
+			// own entries then replace.
+			if key.ShortTag() == "!!merge" {
+				merged, err := fromYAML(value)
+				if err != nil {
+					return nil, err
+				}
+
+				var sources []any
+				if s, ok := merged.([]any); ok {
+					sources = s
+				} else {
+					sources = []any{merged}
+				}
+
+				for _, source := range sources {
+					if source, ok := source.(map[string]any); !ok {
+						return nil, fail(value, "merge key must refer to a mapping or a sequence of mappings")
+					} else {
+						for k, v := range source {
+							if !set[k] {
+								m[k] = v
+							}
+						}
+					}
+				}
+
+				continue
+			}
+
+			if key.Kind == yaml.AliasNode {
+				key = key.Alias
+			}
+
+			if key.Kind != yaml.ScalarNode {
+				return nil, fail(key, "mapping keys must be scalars")
+			} else if set[key.Value] {
+				return nil, fail(key, "mapping key %q is defined more than once", key.Value)
+			}
+
+			v, err := fromYAML(value)
+			if err != nil {
+				return nil, err
+			}
+
+			m[key.Value] = v
+			set[key.Value] = true
+		}
+
+		return m, nil
+	}
+
+	switch tag := node.ShortTag(); tag {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var b bool
+		if err := node.Decode(&b); err != nil {
+			return nil, fail(node, "%s", err)
+		}
+
+		return b, nil
+	case "!!int", "!!float":
+		if f, _, err := big.ParseFloat(node.Value, 10, precision, big.ToNearestEven); err == nil && !f.IsInf() {
+			return number(f), nil
+		}
+
+		// Other notations, such as 0x1f and 1_000, are left to the decoder.
+		var v any
+		if err := node.Decode(&v); err != nil {
+			return nil, fail(node, "%s", err)
+		} else if f, ok := v.(float64); ok && (math.IsInf(f, 0) || math.IsNaN(f)) {
+			return nil, fail(node, "%s cannot be represented as a number", node.Value)
+		} else if f, ok := numeric(v); ok {
+			return number(f), nil
+		} else {
+			return nil, fail(node, "%s cannot be represented as a number", node.Value)
+		}
+	case "!!str", "!!binary", "!!timestamp":
+		return node.Value, nil
+	default:
+		return nil, fail(node, "unsupported tag %q", tag)
+	}
+}
+
+// decodeJSON decodes a JSON value into the same representation as unwrap,
+// with numbers decoded at full precision.
+func decodeJSON(b []byte) (any, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return fromJSON(v)
+}
+
+func fromJSON(v any) (any, error) {
+	switch v := v.(type) {
+	case json.Number:
+		f, _, err := big.ParseFloat(v.String(), 10, precision, big.ToNearestEven)
+		if err != nil {
+			return nil, err
+		}
+
+		return number(f), nil
+	case []any:
+		for i, e := range v {
+			var err error
+			if v[i], err = fromJSON(e); err != nil {
+				return nil, err
+			}
+		}
+
+		return v, nil
+	case map[string]any:
+		for k, e := range v {
+			var err error
+			if v[k], err = fromJSON(e); err != nil {
+				return nil, err
+			}
+		}
+
+		return v, nil
+	default:
+		return v, nil
+	}
+}
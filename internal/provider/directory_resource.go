@@ -0,0 +1,424 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"go.austindrenski.io/gotter/templates"
+)
+
+var (
+	_ resource.Resource                   = (*directoryResource)(nil)
+	_ resource.ResourceWithModifyPlan     = (*directoryResource)(nil)
+	_ resource.ResourceWithValidateConfig = (*directoryResource)(nil)
+)
+
+type directoryResource struct{}
+
+type directoryResourceModel struct {
+	Data                types.Dynamic `tfsdk:"data"`
+	Destination         types.String  `tfsdk:"destination"`
+	DirectoryPermission types.String  `tfsdk:"directory_permission"`
+	FilePermission      types.String  `tfsdk:"file_permission"`
+	Manifest            types.Map     `tfsdk:"manifest"`
+	Source              types.String  `tfsdk:"source"`
+}
+
+// renderedFile is a single file rendered from the source directory.
+type renderedFile struct {
+	content []byte
+	mode    fs.FileMode
+}
+
+func (r directoryResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var model directoryResourceModel
+
+	if resp.Diagnostics.Append(req.Plan.Get(ctx, &model)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	if resp.Diagnostics.Append(r.write(ctx, &model, nil)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
+}
+
+func (r directoryResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var model directoryResourceModel
+
+	if resp.Diagnostics.Append(req.State.Get(ctx, &model)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	manifest, diags := r.manifest(ctx, model.Manifest)
+	if resp.Diagnostics.Append(diags...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.prune(model.Destination.ValueString(), manifest, nil)...)
+}
+
+func (r directoryResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_directory"
+}
+
+func (r directoryResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var model directoryResourceModel
+
+	if resp.Diagnostics.Append(req.Plan.Get(ctx, &model)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	if model.Source.IsUnknown() || model.FilePermission.IsUnknown() {
+		return
+	}
+
+	files, ok, diags := r.renderFiles(ctx, model)
+	if resp.Diagnostics.Append(diags...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !ok {
+		model.Manifest = types.MapUnknown(types.StringType)
+	} else {
+		model.Manifest = r.checksums(files)
+	}
+
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &model)...)
+}
+
+func (r directoryResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var model directoryResourceModel
+
+	if resp.Diagnostics.Append(req.State.Get(ctx, &model)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	manifest, diags := r.manifest(ctx, model.Manifest)
+	if resp.Diagnostics.Append(diags...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	elements := make(map[string]attr.Value, len(manifest))
+
+	// Files that were deleted or edited outside of Terraform are reflected in
+	// the manifest so that the next plan restores them.
+	for name := range manifest {
+		b, err := os.ReadFile(filepath.Join(model.Destination.ValueString(), filepath.FromSlash(name)))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("destination"), "Failed to read file", err.Error())
+			return
+		}
+
+		sha256, _ := checksums(b)
+		elements[name] = types.StringValue(sha256)
+	}
+
+	model.Manifest = types.MapValueMust(types.StringType, elements)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
+}
+
+func (r directoryResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"data": schema.DynamicAttribute{
+				Description: "The data passed to each template",
+				Optional:    true,
+			},
+			"destination": schema.StringAttribute{
+				Description: "The directory to which the rendered files are written",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Required: true,
+			},
+			"directory_permission": schema.StringAttribute{
+				Computed:    true,
+				Default:     stringdefault.StaticString("0755"),
+				Description: "The permissions, in octal, of any directories created for the rendered files",
+				Optional:    true,
+			},
+			"file_permission": schema.StringAttribute{
+				Description: "The permissions, in octal, of the rendered files. Defaults to the permissions of each source file.",
+				Optional:    true,
+			},
+			"manifest": schema.MapAttribute{
+				Computed:    true,
+				Description: "The hex-encoded SHA256 checksum of each rendered file, keyed by its path relative to `destination`",
+				ElementType: types.StringType,
+			},
+			"source": schema.StringAttribute{
+				Description: "The directory of text templates to render. The path of each file relative to `source` is itself rendered as a template, and files whose rendered path is empty, or contains an empty segment, are skipped.",
+				Required:    true,
+			},
+		},
+		Description: "Renders every Go text/template under `source` using the provided `data` into `destination`",
+	}
+}
+
+func (r directoryResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var model, state directoryResourceModel
+
+	if resp.Diagnostics.Append(req.Plan.Get(ctx, &model)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	if resp.Diagnostics.Append(req.State.Get(ctx, &state)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	previous, diags := r.manifest(ctx, state.Manifest)
+	if resp.Diagnostics.Append(diags...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	if resp.Diagnostics.Append(r.write(ctx, &model, previous)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
+}
+
+func (r directoryResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var model directoryResourceModel
+
+	if resp.Diagnostics.Append(req.Config.Get(ctx, &model)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	for name, v := range map[string]types.String{
+		"directory_permission": model.DirectoryPermission,
+		"file_permission":      model.FilePermission,
+	} {
+		if v.IsNull() || v.IsUnknown() {
+			continue
+		}
+
+		if _, err := permission(v.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root(name), "Invalid file permission", err.Error())
+		}
+	}
+}
+
+func (r directoryResource) checksums(files map[string]renderedFile) types.Map {
+	elements := make(map[string]attr.Value, len(files))
+
+	for name, file := range files {
+		sha256, _ := checksums(file.content)
+		elements[name] = types.StringValue(sha256)
+	}
+
+	return types.MapValueMust(types.StringType, elements)
+}
+
+func (r directoryResource) manifest(ctx context.Context, m types.Map) (map[string]string, diag.Diagnostics) {
+	manifest := map[string]string{}
+
+	if m.IsNull() || m.IsUnknown() {
+		return manifest, nil
+	}
+
+	diags := m.ElementsAs(ctx, &manifest, false)
+
+	return manifest, diags
+}
+
+// prune deletes the files in previous that are not in current, along with any
+// directories left empty.
+func (r directoryResource) prune(destination string, previous map[string]string, current map[string]renderedFile) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	destination = filepath.Clean(destination)
+
+	for name := range previous {
+		if _, ok := current[name]; ok {
+			continue
+		}
+
+		file, err := within(destination, name)
+		if err != nil {
+			diags.AddAttributeError(path.Root("destination"), "Failed to delete file", err.Error())
+			continue
+		}
+
+		if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			diags.AddAttributeError(path.Root("destination"), "Failed to delete file", err.Error())
+			continue
+		}
+
+		// Remove any directories left empty below the destination, stopping at
+		// the first that is not.
+		for dir := filepath.Dir(file); dir != destination; dir = filepath.Dir(dir) {
+			if rel, err := filepath.Rel(destination, dir); err != nil || !filepath.IsLocal(rel) {
+				break
+			} else if err := os.Remove(dir); err != nil {
+				break
+			}
+		}
+	}
+
+	return diags
+}
+
+// renderFiles renders every file under the source directory, keyed by the
+// rendered path relative to the destination directory, reporting false if
+// any template read a value that is not known until apply.
+func (r directoryResource) renderFiles(ctx context.Context, model directoryResourceModel) (map[string]renderedFile, bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	var mode fs.FileMode
+	if !model.FilePermission.IsNull() {
+		m, err := permission(model.FilePermission.ValueString())
+		if err != nil {
+			diags.AddAttributeError(path.Root("file_permission"), "Invalid file permission", err.Error())
+			return nil, false, diags
+		}
+
+		mode = m
+	}
+
+	files := map[string]renderedFile{}
+	complete := true
+
+	source := model.Source.ValueString()
+
+	err := filepath.WalkDir(source, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+
+		rel, err := filepath.Rel(source, file)
+		if err != nil {
+			return err
+		}
+
+		rel = filepath.ToSlash(rel)
+
+		name, ok, err := r.renderText(ctx, rel, rel, model.Data)
+		if err != nil {
+			return err
+		} else if !ok {
+			complete = false
+			return nil
+		} else if slices.Contains(strings.Split(name, "/"), "") {
+			return nil
+		} else if _, err := within("", name); err != nil {
+			return fmt.Errorf("%q renders to %q: %w", rel, name, err)
+		}
+
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		content, ok, err := r.renderText(ctx, rel, string(b), model.Data)
+		if err != nil {
+			return err
+		} else if !ok {
+			complete = false
+			return nil
+		}
+
+		if _, ok := files[name]; ok {
+			return fmt.Errorf("%q and another file both render to %q", rel, name)
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if model.FilePermission.IsNull() {
+			files[name] = renderedFile{content: []byte(content), mode: info.Mode().Perm()}
+		} else {
+			files[name] = renderedFile{content: []byte(content), mode: mode}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		diags.AddAttributeError(path.Root("source"), "Failed to render directory", err.Error())
+		return nil, false, diags
+	}
+
+	return files, complete, diags
+}
+
+func (r directoryResource) renderText(ctx context.Context, name string, text string, data types.Dynamic) (string, bool, error) {
+	t, err := templates.Parse(ctx, name, text, templates.WithFuncs(functions), withDefinedFuncs(text))
+	if err != nil {
+		return "", false, err
+	}
+
+	return render(ctx, t, data)
+}
+
+// write renders the source directory into the destination directory,
+// pruning any files in previous that are no longer rendered.
+func (r directoryResource) write(ctx context.Context, model *directoryResourceModel, previous map[string]string) diag.Diagnostics {
+	files, _, diags := r.renderFiles(ctx, *model)
+	if diags.HasError() {
+		return diags
+	}
+
+	dirPerm, err := permission(model.DirectoryPermission.ValueString())
+	if err != nil {
+		diags.AddAttributeError(path.Root("directory_permission"), "Invalid file permission", err.Error())
+		return diags
+	}
+
+	destination := model.Destination.ValueString()
+
+	for name, file := range files {
+		if target, err := within(destination, name); err != nil {
+			diags.AddAttributeError(path.Root("destination"), "Failed to write file", err.Error())
+			return diags
+		} else if err := write(target, file.content, file.mode, dirPerm); err != nil {
+			diags.AddAttributeError(path.Root("destination"), "Failed to write file", err.Error())
+			return diags
+		}
+	}
+
+	if diags.Append(r.prune(destination, previous, files)...); diags.HasError() {
+		return diags
+	}
+
+	model.Manifest = r.checksums(files)
+
+	return diags
+}
+
+// within joins a rendered file name onto the destination directory, rejecting
+// absolute names and names that would otherwise resolve outside of it.
+func within(destination string, name string) (string, error) {
+	local := filepath.FromSlash(name)
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("%q is not a path within the destination directory", name)
+	}
+
+	return filepath.Join(destination, local), nil
+}
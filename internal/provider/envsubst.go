@@ -0,0 +1,228 @@
+package provider
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type envsubstText string
+
+// envsubstVariable is a `${name}` placeholder, where op is '-' for
+// `${name-word}`, '?' for `${name?word}` and zero otherwise. As in the shell,
+// the word is used when the variable is not set, or with colon, as in
+// `${name:-word}`, when it is not set or empty.
+type envsubstVariable struct {
+	colon bool
+	line  int
+	name  string
+	op    byte
+	word  []any
+}
+
+// parseEnvsubst parses text holding shell-style placeholders into
+// envsubstText and envsubstVariable nodes. `$${` is read as a literal `${`.
+func parseEnvsubst(text string) ([]any, error) {
+	nodes, _, err := parseEnvsubstWord(text, 0, false)
+
+	return nodes, err
+}
+
+// parseEnvsubstWord parses text from pos until the end of the text or, when
+// nested, until the `}` that closes the enclosing placeholder, returning the
+// position of that `}`.
+func parseEnvsubstWord(text string, pos int, nested bool) ([]any, int, error) {
+	var nodes []any
+	var b strings.Builder
+
+	flush := func() {
+		if b.Len() > 0 {
+			nodes = append(nodes, envsubstText(b.String()))
+			b.Reset()
+		}
+	}
+
+	for pos < len(text) {
+		if strings.HasPrefix(text[pos:], "$${") {
+			b.WriteString("${")
+			pos += 3
+		} else if strings.HasPrefix(text[pos:], "${") {
+			flush()
+
+			v, end, err := parseEnvsubstVariable(text, pos)
+			if err != nil {
+				return nil, 0, err
+			}
+
+			nodes = append(nodes, v)
+			pos = end
+		} else if nested && text[pos] == '}' {
+			break
+		} else {
+			b.WriteByte(text[pos])
+			pos++
+		}
+	}
+
+	flush()
+
+	return nodes, pos, nil
+}
+
+// parseEnvsubstVariable parses the placeholder starting at pos, returning the
+// position after its closing `}`.
+func parseEnvsubstVariable(text string, pos int) (envsubstVariable, int, error) {
+	v := envsubstVariable{line: lineNumber(text, pos)}
+
+	start := pos + 2
+	end := start
+
+	for end < len(text) && envsubstName(text[end]) {
+		end++
+	}
+
+	v.name = text[start:end]
+
+	if v.name == "" && end < len(text) && text[end] != '}' && text[end] != ':' {
+		return v, 0, fmt.Errorf("line %d: invalid character %q in placeholder", v.line, text[end])
+	} else if v.name == "" {
+		return v, 0, fmt.Errorf("line %d: empty placeholder", v.line)
+	} else if slices.Contains(strings.Split(v.name, "."), "") {
+		return v, 0, fmt.Errorf("line %d: invalid placeholder name %q", v.line, v.name)
+	}
+
+	if strings.HasPrefix(text[end:], ":-") || strings.HasPrefix(text[end:], ":?") {
+		v.colon = true
+		end++
+	}
+
+	if end < len(text) && (text[end] == '-' || text[end] == '?') {
+		v.op = text[end]
+
+		word, next, err := parseEnvsubstWord(text, end+1, true)
+		if err != nil {
+			return v, 0, err
+		}
+
+		v.word = word
+		end = next
+	}
+
+	if end == len(text) {
+		return v, 0, fmt.Errorf("line %d: unclosed placeholder %q", v.line, v.name)
+	} else if text[end] != '}' {
+		return v, 0, fmt.Errorf("line %d: invalid character %q in placeholder %q", v.line, text[end], v.name)
+	}
+
+	return v, end + 1, nil
+}
+
+// envsubstName reports whether the byte can appear in a placeholder name,
+// where dots separate the segments of a nested path.
+func envsubstName(c byte) bool {
+	return c == '_' || c == '.' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// envsubstRenderer substitutes placeholders with values from the unwrapped
+// data. Strict renderers fail on placeholders without a default whose value
+// is missing or null.
+type envsubstRenderer struct {
+	b      strings.Builder
+	data   any
+	strict bool
+}
+
+func (r *envsubstRenderer) render(nodes []any) error {
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case envsubstText:
+			r.b.WriteString(string(node))
+		case envsubstVariable:
+			v, set, err := r.lookup(node.name)
+			if err != nil {
+				return err
+			}
+
+			s, err := stringify(v)
+			if err != nil {
+				return err
+			}
+
+			// Null values are not set, as in the shell.
+			unset := !set
+			if node.colon {
+				unset = s == ""
+			}
+
+			if node.op == '-' && unset {
+				err = r.render(node.word)
+			} else if node.op == '?' && unset {
+				err = r.fail(node)
+			} else if node.op == 0 && !set && r.strict {
+				err = fmt.Errorf("line %d: variable %q is not set", node.line, node.name)
+			} else {
+				r.b.WriteString(s)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// fail reports the error of a `${name?word}` or `${name:?word}` placeholder,
+// rendering its word as the message in the style of the shell.
+func (r *envsubstRenderer) fail(node envsubstVariable) error {
+	w := envsubstRenderer{data: r.data, strict: r.strict}
+	if err := w.render(node.word); err != nil {
+		return err
+	}
+
+	if msg := w.b.String(); msg != "" {
+		return fmt.Errorf("line %d: %s: %s", node.line, node.name, msg)
+	}
+
+	if !node.colon {
+		return fmt.Errorf("line %d: %s: parameter not set", node.line, node.name)
+	}
+
+	return fmt.Errorf("line %d: %s: parameter null or not set", node.line, node.name)
+}
+
+// lookup resolves a dotted name against the data, where segments index into
+// objects by key and into lists by position. It reports false if the value is
+// missing or null.
+func (r *envsubstRenderer) lookup(name string) (any, bool, error) {
+	v := r.data
+
+	for _, segment := range strings.Split(name, ".") {
+		switch c := v.(type) {
+		case unknown:
+			return nil, false, errUnknown
+		case map[string]any:
+			v = c[segment]
+		case []any:
+			if i, err := strconv.Atoi(segment); err != nil || i < 0 || i >= len(c) {
+				v = nil
+			} else {
+				v = c[i]
+			}
+		default:
+			v = nil
+		}
+
+		if v == nil {
+			return nil, false, nil
+		}
+	}
+
+	if !known(v) {
+		return nil, false, errUnknown
+	}
+
+	return v, true, nil
+}
@@ -0,0 +1,553 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math/big"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// escapeModes lists the supported escape modes.
+var escapeModes = []string{"json", "none", "yaml"}
+
+// escapeState is the lexical state of the output at a given point.
+type escapeState uint8
+
+const (
+	// stateText is outside of any string, e.g. a JSON value position or a
+	// YAML plain scalar.
+	stateText escapeState = iota
+	// stateDouble is inside a double-quoted JSON string or YAML scalar.
+	stateDouble
+	// stateSingle is inside a single-quoted YAML scalar.
+	stateSingle
+	// stateComment is inside a YAML comment.
+	stateComment
+)
+
+// escapeContext describes the output preceding an action.
+type escapeContext struct {
+	state escapeState
+	// backslash reports whether the preceding character escapes the next one
+	// in a double-quoted string.
+	backslash bool
+	// flow is the depth of YAML flow collections.
+	flow int
+	// started reports whether a YAML plain scalar has begun on this line.
+	started bool
+	// space reports whether the preceding character is whitespace or the
+	// start of the output.
+	space bool
+}
+
+// join returns the context in which the output continues after two
+// alternative branches, e.g. of an if action. The branches may disagree on
+// whether a YAML plain scalar has begun, in which case it is assumed to have
+// begun, since embedding a value is the more restrictive of the two.
+func join(a escapeContext, b escapeContext) (escapeContext, bool) {
+	if a == b {
+		return a, true
+	} else if a.state != b.state || a.backslash != b.backslash || a.flow != b.flow {
+		return a, false
+	}
+
+	return escapeContext{
+		state:   a.state,
+		flow:    a.flow,
+		started: a.started || b.started,
+		space:   a.space && b.space,
+	}, true
+}
+
+// escape rewrites the parse trees of t and the templates it invokes so that
+// each action is escaped according to the JSON or YAML context in which it
+// appears, in the spirit of html/template.
+//
+// In JSON, values are encoded as JSON, or as the contents of a string when
+// the action is inside a string. In YAML, values that form a whole scalar are
+// quoted whenever they would otherwise be read as another type or break the
+// document, values inside quoted scalars are escaped, and values embedded in
+// a larger plain scalar or a comment are rejected if they would break it.
+// Block scalars are not tracked. The output of json and include is written
+// as is where it is already escaped, see escaper.action.
+func escape(t *template.Template, mode string) error {
+	if mode == "" || mode == "none" {
+		return nil
+	}
+
+	t.Funcs(escapers)
+
+	e := escaper{
+		commands: map[*parse.ActionNode]*parse.CommandNode{},
+		ends:     map[string]escapeContext{},
+		json:     mode == "json",
+		starts:   map[string]escapeContext{},
+		t:        t,
+	}
+
+	_, err := e.call(t.Name(), escapeContext{space: true})
+
+	return err
+}
+
+type escaper struct {
+	// commands holds the escaping command appended to each action, so that
+	// actions can be escaped again when their context changes.
+	commands map[*parse.ActionNode]*parse.CommandNode
+	// ends holds the context at the end of each escaped template.
+	ends map[string]escapeContext
+	json bool
+	// starts holds the context in which each template is invoked.
+	starts map[string]escapeContext
+	t      *template.Template
+}
+
+// call escapes the named template in the given context, returning the
+// context at its end.
+func (e escaper) call(name string, c escapeContext) (escapeContext, error) {
+	if start, ok := e.starts[name]; !ok {
+	} else if start != c {
+		return c, fmt.Errorf("template %q is invoked in different contexts", name)
+	} else if end, ok := e.ends[name]; ok {
+		return end, nil
+	} else {
+		// The template invokes itself, and is assumed to end in the context
+		// in which it started.
+		return c, nil
+	}
+
+	t := e.t.Lookup(name)
+	if t == nil || t.Tree == nil || t.Tree.Root == nil {
+		return c, nil
+	}
+
+	e.starts[name] = c
+
+	end, err := e.list(t.Tree.Root, c)
+	if err != nil {
+		return c, err
+	}
+
+	e.ends[name] = end
+
+	return end, nil
+}
+
+func (e escaper) list(node *parse.ListNode, c escapeContext) (escapeContext, error) {
+	if node == nil {
+		return c, nil
+	}
+
+	var err error
+
+	for i, n := range node.Nodes {
+		switch n := n.(type) {
+		case *parse.ActionNode:
+			var next parse.Node
+			if i+1 < len(node.Nodes) {
+				next = node.Nodes[i+1]
+			}
+
+			c, err = e.action(n, c, next)
+		case *parse.IfNode:
+			c, err = e.branch("if", &n.BranchNode, c)
+		case *parse.RangeNode:
+			c, err = e.branch("range", &n.BranchNode, c)
+		case *parse.TemplateNode:
+			c, err = e.call(n.Name, c)
+		case *parse.TextNode:
+			c = e.text(n.Text, c)
+		case *parse.WithNode:
+			c, err = e.branch("with", &n.BranchNode, c)
+		}
+
+		if err != nil {
+			return c, err
+		}
+	}
+
+	return c, nil
+}
+
+// branch escapes the branches of an if, range or with action. The body of a
+// range action is escaped again if it ends in a context other than the one
+// in which it started, since it may be executed more than once.
+func (e escaper) branch(kind string, node *parse.BranchNode, c escapeContext) (escapeContext, error) {
+	start := c
+
+	end, err := e.list(node.List, start)
+	if err != nil {
+		return c, err
+	}
+
+	if kind == "range" && end != start {
+		if start, ok := join(start, end); !ok {
+			return c, fmt.Errorf("line %d: {{range}} body ends in a different context than it starts", node.Line)
+		} else if end, err = e.list(node.List, start); err != nil {
+			return c, err
+		}
+	}
+
+	other, err := e.list(node.ElseList, c)
+	if err != nil {
+		return c, err
+	}
+
+	if end, ok := join(end, other); !ok {
+		return c, fmt.Errorf("line %d: {{%s}} branches end in different contexts", node.Line, kind)
+	} else {
+		return end, nil
+	}
+}
+
+// action appends the escaper for the context to the action, unless it only
+// declares variables or its output is already escaped.
+//
+// The output of json is already encoded where a JSON value can appear. The
+// template named by include is escaped in the context of the action, as if
+// invoked with template, so its output is written as is.
+func (e escaper) action(node *parse.ActionNode, c escapeContext, next parse.Node) (escapeContext, error) {
+	if len(node.Pipe.Decl) > 0 {
+		return c, nil
+	}
+
+	cmds := node.Pipe.Cmds
+	if _, ok := e.commands[node]; ok {
+		cmds = cmds[:len(cmds)-1]
+	}
+
+	last := cmds[len(cmds)-1].Args
+	ident, _ := last[0].(*parse.IdentifierNode)
+
+	if ident != nil && ident.Ident == "include" && len(last) == 3 {
+		if name, ok := last[1].(*parse.StringNode); ok {
+			e.unescape(node)
+			return e.call(name.Text, c)
+		}
+	}
+
+	var args []parse.Node
+
+	switch {
+	case ident != nil && ident.Ident == "json" && c.state == stateText && (e.json || !c.started && e.terminated(next, c)):
+		// The output is already a JSON value, which is also a YAML flow node.
+	case e.json && c.state == stateText:
+		args = []parse.Node{identifier(node.Pos, "_json")}
+	case e.json:
+		args = []parse.Node{identifier(node.Pos, "_json_string")}
+	case c.state == stateComment:
+		args = []parse.Node{identifier(node.Pos, "_yaml_comment")}
+	case c.state == stateDouble:
+		args = []parse.Node{identifier(node.Pos, "_yaml_double")}
+	case c.state == stateSingle:
+		args = []parse.Node{identifier(node.Pos, "_yaml_single")}
+	case !c.started && e.terminated(next, c):
+		args = []parse.Node{identifier(node.Pos, "_yaml")}
+	default:
+		args = []parse.Node{identifier(node.Pos, "_yaml_plain"), &parse.BoolNode{NodeType: parse.NodeBool, Pos: node.Pos, True: c.flow > 0}}
+	}
+
+	if cmd, ok := e.commands[node]; args == nil {
+		e.unescape(node)
+	} else if ok {
+		cmd.Args = args
+	} else {
+		cmd = command(node.Pos, args...)
+		node.Pipe.Cmds = append(node.Pipe.Cmds, cmd)
+		e.commands[node] = cmd
+	}
+
+	if c.state == stateText {
+		c.started = true
+	}
+
+	c.backslash = false
+	c.space = false
+
+	return c, nil
+}
+
+// unescape removes the escaper appended to the action, if any, for when the
+// action is escaped again in a context in which its output is already
+// escaped.
+func (e escaper) unescape(node *parse.ActionNode) {
+	if cmd, ok := e.commands[node]; ok {
+		node.Pipe.Cmds = slices.DeleteFunc(node.Pipe.Cmds, func(c *parse.CommandNode) bool { return c == cmd })
+		delete(e.commands, node)
+	}
+}
+
+// terminated reports whether a YAML plain scalar would end immediately after
+// the action, i.e. whether the action forms a whole scalar.
+func (e escaper) terminated(next parse.Node, c escapeContext) bool {
+	text, ok := next.(*parse.TextNode)
+	if next == nil {
+		return true
+	} else if !ok {
+		return false
+	} else if len(text.Text) == 0 {
+		return true
+	}
+
+	switch text.Text[0] {
+	case ' ', '\t', '\r', '\n':
+		return true
+	case ':':
+		return len(text.Text) == 1 || strings.ContainsRune(" \t\r\n", rune(text.Text[1]))
+	case ',', ']', '}':
+		return c.flow > 0
+	default:
+		return false
+	}
+}
+
+// text advances the context past the literal text.
+func (e escaper) text(s []byte, c escapeContext) escapeContext {
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+
+		switch c.state {
+		case stateComment:
+			if ch == '\n' {
+				c.state = stateText
+				c.started = false
+			}
+		case stateDouble:
+			if c.backslash {
+				c.backslash = false
+			} else if ch == '\\' {
+				c.backslash = true
+			} else if ch == '"' {
+				c.state = stateText
+				c.started = true
+			}
+		case stateSingle:
+			if ch != '\'' {
+			} else if i+1 < len(s) && s[i+1] == '\'' {
+				i++
+			} else {
+				c.state = stateText
+				c.started = true
+			}
+		case stateText:
+			if e.json {
+				if ch == '"' {
+					c.state = stateDouble
+				}
+			} else {
+				c = e.yaml(s, i, c)
+			}
+		}
+
+		c.space = strings.ContainsRune(" \t\r\n", rune(ch))
+	}
+
+	return c
+}
+
+// yaml advances the context past the character at s[i] outside of any
+// quoted scalar or comment.
+func (e escaper) yaml(s []byte, i int, c escapeContext) escapeContext {
+	// indicator reports whether the character is followed by whitespace or
+	// the end of the text.
+	indicator := i+1 == len(s) || strings.ContainsRune(" \t\r\n", rune(s[i+1]))
+
+	switch ch := s[i]; {
+	case ch == '\n':
+		c.started = false
+	case ch == ' ' || ch == '\t' || ch == '\r':
+	case ch == '#' && (c.space || !c.started):
+		c.state = stateComment
+	case ch == '"' && !c.started:
+		c.state = stateDouble
+	case ch == '\'' && !c.started:
+		c.state = stateSingle
+	case (ch == '[' || ch == '{') && !c.started:
+		c.flow++
+	case (ch == ']' || ch == '}') && c.flow > 0:
+		c.flow--
+		c.started = true
+	case ch == ',' && c.flow > 0:
+		c.started = false
+	case ch == ':' && indicator:
+		c.started = false
+	case (ch == '-' || ch == '?') && indicator && !c.started:
+	default:
+		c.started = true
+	}
+
+	return c
+}
+
+// escapers holds the functions that escape inserts into parse trees.
+var escapers = template.FuncMap{
+	"_json": func(v any) (string, error) {
+		if !known(v) {
+			return "", errUnknown
+		}
+
+		return marshal(v)
+	},
+	"_json_string": func(v any) (string, error) {
+		if !known(v) {
+			return "", errUnknown
+		}
+
+		return quoted(v)
+	},
+	"_yaml": func(v any) (string, error) {
+		if !known(v) {
+			return "", errUnknown
+		} else if s, ok := v.(string); ok && plain(s) {
+			return s, nil
+		} else {
+			return marshal(v)
+		}
+	},
+	"_yaml_comment": func(v any) (string, error) {
+		if !known(v) {
+			return "", errUnknown
+		} else if s, err := stringify(v); err != nil {
+			return "", err
+		} else if strings.ContainsAny(s, "\r\n") {
+			return "", fmt.Errorf("value %q cannot be embedded in a YAML comment", s)
+		} else {
+			return s, nil
+		}
+	},
+	"_yaml_double": func(v any) (string, error) {
+		if !known(v) {
+			return "", errUnknown
+		}
+
+		return quoted(v)
+	},
+	"_yaml_plain": func(flow bool, v any) (string, error) {
+		if !known(v) {
+			return "", errUnknown
+		} else if s, err := stringify(v); err != nil {
+			return "", err
+		} else if strings.ContainsAny(s, "\r\n") || strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+			return "", fmt.Errorf("value %q cannot be embedded in a YAML plain scalar, quote the scalar instead", s)
+		} else if flow && strings.ContainsAny(s, ",[]{}") {
+			return "", fmt.Errorf("value %q cannot be embedded in a YAML plain scalar in a flow collection, quote the scalar instead", s)
+		} else {
+			return s, nil
+		}
+	},
+	"_yaml_single": func(v any) (string, error) {
+		if !known(v) {
+			return "", errUnknown
+		} else if s, err := stringify(v); err != nil {
+			return "", err
+		} else if strings.ContainsAny(s, "\r\n") {
+			return "", fmt.Errorf("value %q cannot be embedded in a single-quoted YAML scalar, use a double-quoted scalar instead", s)
+		} else {
+			return strings.ReplaceAll(s, "'", "''"), nil
+		}
+	},
+}
+
+// marshal encodes the value as JSON, which is also valid as a YAML flow
+// node. Arbitrary precision numbers are encoded as numbers, not strings.
+func marshal(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "null", nil
+	case *big.Float:
+		return v.Text('g', -1), nil
+	case []any:
+		elements := make([]string, len(v))
+
+		for i, v := range v {
+			if s, err := marshal(v); err != nil {
+				return "", err
+			} else {
+				elements[i] = s
+			}
+		}
+
+		return "[" + strings.Join(elements, ",") + "]", nil
+	case map[string]any:
+		var elements []string
+
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			if key, err := marshal(k); err != nil {
+				return "", err
+			} else if value, err := marshal(v[k]); err != nil {
+				return "", err
+			} else {
+				elements = append(elements, key+":"+value)
+			}
+		}
+
+		return "{" + strings.Join(elements, ",") + "}", nil
+	default:
+		b := bytes.Buffer{}
+
+		encoder := json.NewEncoder(&b)
+		encoder.SetEscapeHTML(false)
+
+		if err := encoder.Encode(v); err != nil {
+			return "", err
+		}
+
+		return strings.TrimSuffix(b.String(), "\n"), nil
+	}
+}
+
+// quoted returns the value as the contents of a double-quoted string, which
+// is valid in both JSON and YAML.
+func quoted(v any) (string, error) {
+	s, err := stringify(v)
+	if err != nil {
+		return "", err
+	}
+
+	m, err := marshal(s)
+	if err != nil {
+		return "", err
+	}
+
+	return m[1 : len(m)-1], nil
+}
+
+// stringify returns the text of a value embedded in a string, where null is
+// empty and collections are encoded as JSON.
+func stringify(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []any, map[string]any, *big.Float:
+		return marshal(v)
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// plain reports whether the string can be written as a YAML plain scalar
+// without being read as another type or changing the structure of the
+// document.
+func plain(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s {
+		return false
+	} else if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`+.0123456789") {
+		return false
+	} else if strings.ContainsAny(s, ",[]{}\r\n\t") || strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return false
+	}
+
+	switch strings.ToLower(s) {
+	case "~", "null", "true", "false", "yes", "no", "on", "off", "y", "n":
+		return false
+	}
+
+	return !slices.ContainsFunc([]rune(s), func(r rune) bool { return r < ' ' || r == 0x7f })
+}
@@ -2,14 +2,16 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
-	"strings"
+	"path/filepath"
+	"slices"
 	"text/template"
 
 	"github.com/hashicorp/terraform-plugin-framework/function"
 	"github.com/hashicorp/terraform-plugin-framework/types"
-	"go.austindrenski.io/gotter/templates"
 )
 
 var (
@@ -17,6 +19,27 @@ var (
 	_ function.StringParameterValidator = (*execute)(nil)
 )
 
+// optionsParameter is the variadic parameter of the functions that execute Go
+// templates, holding objects that configure the template.
+var optionsParameter = function.DynamicParameter{
+	AllowUnknownValues: true,
+	Description: "Objects configuring the template, where later objects take precedence. " +
+		"`engine` selects `go` or `mustache` templates. " +
+		"`escape` escapes each action for `none`, `json` or `yaml` output. " +
+		"`extends` names a layout file whose blocks the template overrides. " +
+		"`layers` lists values that are deep merged over the data in order. " +
+		"`left_delim` and `right_delim` replace the `{{` and `}}` action delimiters. " +
+		"`list_merge` selects whether layers `replace`, `append` or merge lists by `key`. " +
+		"`list_merge_key` names the attribute that list elements are merged by. " +
+		"`lstrip_blocks` removes the indentation before block actions. " +
+		"`missing_key` is `default` to print `<no value>`, `zero` to print nothing or `error` to fail on missing keys. " +
+		"`partials` maps names to templates that the template can invoke. " +
+		"`plugins` maps function names to programs listed in the `GOTTER_PLUGIN_ALLOWLIST` environment variable. " +
+		"`template` names the template to execute. " +
+		"`trim_blocks` removes the newline after block actions",
+	Name: "options",
+}
+
 type execute struct {
 	file bool
 	name string
@@ -25,31 +48,34 @@ type execute struct {
 func (f execute) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
 	templateParameter := function.StringParameter{
 		AllowNullValue: false,
-		Validators: []function.StringParameterValidator{
-			execute{},
-		},
 	}
 
+	// Templates are only parsed once the options are known, since the options
+	// can change the delimiters.
 	if f.file {
 		templateParameter.Description = "The text template file"
 		templateParameter.Name = "file"
+		templateParameter.Validators = []function.StringParameterValidator{f}
 	} else {
 		templateParameter.Description = "The text template"
 		templateParameter.Name = "text"
 	}
 
 	resp.Definition = function.Definition{
-		Description: fmt.Sprintf("Executes a Go text/template from `%s` using the provided `data`", templateParameter.GetName()),
+		Description: fmt.Sprintf("Executes a Go text/template from `%s` using the provided `data`, over which the `layers` option deep merges further values. "+
+			"Functions backed by local executables are configured with the `plugins` option and may only run programs listed in the `%s` environment variable", templateParameter.GetName(), pluginAllowlist),
 		Parameters: []function.Parameter{
 			templateParameter,
 			function.DynamicParameter{
-				AllowNullValue: true,
-				Description:    "The data passed to the template",
-				Name:           "data",
+				AllowNullValue:     true,
+				AllowUnknownValues: true,
+				Description:        "The data passed to the template, over which the `layers` option is merged",
+				Name:               "data",
 			},
 		},
-		Return:  function.StringReturn{},
-		Summary: fmt.Sprintf("Executes a Go text/template from `%s` using the provided `data`", templateParameter.GetName()),
+		Return:            function.StringReturn{},
+		Summary:           fmt.Sprintf("Executes a Go text/template from `%s` using the provided `data`", templateParameter.GetName()),
+		VariadicParameter: optionsParameter,
 	}
 }
 
@@ -60,79 +86,231 @@ func (f execute) Metadata(_ context.Context, _ function.MetadataRequest, resp *f
 func (f execute) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
 	var text string
 	var data types.Dynamic
+	var values []types.Dynamic
 
-	if err := req.Arguments.Get(ctx, &text, &data); err != nil {
+	if err := req.Arguments.Get(ctx, &text, &data, &values); err != nil {
 		resp.Error = function.ConcatFuncErrors(err)
 		return
 	}
 
-	t, err := f.parse(ctx, text)
-	if err != nil {
-		resp.Error = function.NewArgumentFuncError(0, err.Error())
+	if s, ok, ferr := f.run(ctx, text, data, values); ferr != nil {
+		resp.Error = ferr
+		return
+	} else if !ok {
+		resp.Error = resp.Result.Set(ctx, types.StringUnknown())
+		return
+	} else if err := resp.Result.Set(ctx, s); err != nil {
+		resp.Error = err
 		return
 	}
+}
 
-	b := strings.Builder{}
-	if err := templates.Execute(ctx, t, unwrap(data), &b); err != nil {
-		resp.Error = function.NewFuncError(err.Error())
-		return
+// run renders the template with the data as configured by the options
+// objects, reporting false if any of the options or any value that the
+// template read is not yet known. Errors are attributed to the arguments of
+// execute, with the options objects following the template and the data.
+func (f execute) run(ctx context.Context, text string, data types.Dynamic, values []types.Dynamic) (string, bool, *function.FuncError) {
+	opts, entry, parent, ok, ferr := options(values)
+	if ferr != nil || !ok {
+		return "", false, ferr
 	}
 
-	if err := resp.Result.Set(ctx, b.String()); err != nil {
-		resp.Error = err
-		return
+	v, err := opts.merge(unwrap(data))
+	if err != nil {
+		return "", false, function.NewFuncError(err.Error())
+	}
+
+	if opts.engine == "mustache" {
+		return f.mustache(text, v, opts, len(values)-1)
+	}
+
+	return f.template(ctx, text, v, opts, entry, parent)
+}
+
+// options merges the options objects, where later objects take precedence,
+// reporting false if any of them, apart from their layers, is not yet known.
+// It also returns the index of the object that names the template to execute
+// and the index of the object that names the layout it extends.
+func options(values []types.Dynamic) (executeOptions, int, int, bool, *function.FuncError) {
+	var opts executeOptions
+	var entry int
+	var parent int
+
+	for i, v := range values {
+		name, extends := opts.template, opts.extends
+
+		if !known(settings(v)) {
+			return opts, 0, 0, false, nil
+		} else if err := opts.set(v); err != nil {
+			return opts, 0, 0, false, function.NewArgumentFuncError(int64(2+i), err.Error())
+		}
+
+		if opts.template != name {
+			entry = i
+		}
+
+		if opts.extends != extends {
+			parent = i
+		}
+	}
+
+	if opts.listMerge == "key" && opts.listMergeKey == "" {
+		return opts, 0, 0, false, function.NewArgumentFuncError(int64(2+len(values)-1), "option \"list_merge_key\" must be set when option \"list_merge\" is \"key\"")
+	}
+
+	return opts, entry, parent, true, nil
+}
+
+// template renders a Go template with unwrapped data, see parse.
+func (f execute) template(ctx context.Context, text string, data any, opts executeOptions, entry int, parent int) (string, bool, *function.FuncError) {
+	t, ferr := f.parse(ctx, text, opts, entry, parent)
+	if ferr != nil {
+		return "", false, ferr
+	}
+
+	if s, ok, err := renderUnwrapped(ctx, t, data); err != nil {
+		return "", false, function.NewFuncError(err.Error())
+	} else {
+		return s, ok, nil
+	}
+}
+
+// parse parses a Go template along with its layouts and partials, where entry
+// is the index of the options object that names the template to execute and
+// parent is the index of the options object that names the layout it extends.
+func (f execute) parse(ctx context.Context, text string, opts executeOptions, entry int, parent int) (*template.Template, *function.FuncError) {
+	chain, err := f.layouts(text, opts)
+	if err != nil && opts.extends != nil {
+		return nil, function.NewArgumentFuncError(int64(2+parent), err.Error())
+	} else if err != nil {
+		return nil, function.NewArgumentFuncError(0, err.Error())
+	}
+
+	// Functions declared by any of the layouts or partials can be called from
+	// all of them.
+	var texts []string
+	for _, l := range chain {
+		texts = append(texts, l.text)
+	}
+
+	partials := make([]map[string]string, len(opts.partials))
+	for i, m := range opts.partials {
+		partials[i] = make(map[string]string, len(m))
+
+		for k, v := range m {
+			partials[i][k] = opts.prepare(v)
+			texts = append(texts, v)
+		}
+	}
+
+	parse := append(opts.parse(), withDefinedFuncs(texts...))
+
+	t, err := parseLayouts(ctx, chain, opts.prepare, parse...)
+	if err != nil {
+		return nil, function.NewArgumentFuncError(0, err.Error())
+	}
+
+	if i, err := parsePartials(ctx, t, partials, parse...); err != nil {
+		return nil, function.NewArgumentFuncError(int64(2+i), err.Error())
+	}
+
+	// The escapers print null values themselves, as null or as nothing
+	// within a string.
+	if opts.missingKey == "zero" && (opts.escape == "" || opts.escape == "none") {
+		blank(t)
+	}
+
+	if opts.template != nil {
+		if t, err = lookup(t, *opts.template); err != nil {
+			return nil, function.NewArgumentFuncError(int64(2+entry), err.Error())
+		}
+	}
+
+	if err := escape(t, opts.escape); err != nil {
+		return nil, function.NewArgumentFuncError(0, err.Error())
 	}
+
+	return t, nil
 }
 
-func (f execute) ValidateParameterString(ctx context.Context, req function.StringParameterValidatorRequest, resp *function.StringParameterValidatorResponse) {
-	v := req.Value.ValueString()
+// layouts returns the inheritance chain of the template text, or of the
+// template file named by text.
+func (f execute) layouts(text string, opts executeOptions) ([]layout, error) {
+	entry := layout{text: text}
 
 	if f.file {
-		if stat, err := os.Stat(v); err != nil {
-			resp.Error = function.NewArgumentFuncError(req.ArgumentPosition, err.Error())
-			return
-		} else if stat.IsDir() {
-			resp.Error = function.NewArgumentFuncError(req.ArgumentPosition, fmt.Sprintf("%q is a directory", v))
-			return
-		} else if stat.Size() == 0 {
-			resp.Error = function.NewArgumentFuncError(req.ArgumentPosition, fmt.Sprintf("%q is empty", v))
-			return
-		} else if _, err := templates.ParseFile(ctx, v, templates.WithFuncs(templates.Functions)); err != nil {
-			resp.Error = function.NewArgumentFuncError(req.ArgumentPosition, err.Error())
-			return
+		b, err := os.ReadFile(text)
+		if err != nil {
+			return nil, err
 		}
-	} else if _, err := templates.Parse(ctx, "", v, templates.WithFuncs(templates.Functions)); err != nil {
-		resp.Error = function.NewArgumentFuncError(req.ArgumentPosition, err.Error())
+
+		entry = layout{name: filepath.Base(text), path: text, text: string(b)}
 	}
+
+	left, right := opts.delims()
+
+	return layouts(entry, opts.extends, left, right)
 }
 
-func (f execute) parse(ctx context.Context, text string) (*template.Template, error) {
+// mustache renders a Mustache template with the unwrapped data, where last is
+// the index of the last options object.
+func (f execute) mustache(text string, data any, opts executeOptions, last int) (string, bool, *function.FuncError) {
+	if opts.extends != nil {
+		return "", false, function.NewArgumentFuncError(int64(2+last), "option \"extends\" is not supported by the mustache engine")
+	} else if opts.plugins != nil {
+		return "", false, function.NewArgumentFuncError(int64(2+last), "option \"plugins\" is not supported by the mustache engine")
+	} else if opts.template != nil {
+		return "", false, function.NewArgumentFuncError(int64(2+last), "option \"template\" is not supported by the mustache engine")
+	} else if opts.escape != "" && opts.escape != "none" {
+		return "", false, function.NewArgumentFuncError(int64(2+last), "option \"escape\" is not supported by the mustache engine")
+	} else if opts.trimBlocks || opts.lstripBlocks {
+		return "", false, function.NewArgumentFuncError(int64(2+last), "options \"lstrip_blocks\" and \"trim_blocks\" are not supported by the mustache engine")
+	}
+
 	if f.file {
-		return templates.ParseFile(ctx, text, templates.WithFuncs(templates.Functions))
-	} else {
-		return templates.Parse(ctx, "", text, templates.WithFuncs(templates.Functions))
+		b, err := os.ReadFile(text)
+		if err != nil {
+			return "", false, function.NewArgumentFuncError(0, err.Error())
+		}
+
+		text = string(b)
+	}
+
+	left, right := opts.delims()
+
+	r := mustacheRenderer{
+		left:     left,
+		partials: map[string]string{},
+		right:    right,
+		strict:   opts.missingKey == "error",
+	}
+
+	for i, partials := range opts.partials {
+		for _, key := range slices.Sorted(maps.Keys(partials)) {
+			if _, ok := r.partials[key]; ok {
+				return "", false, function.NewArgumentFuncError(int64(2+i), fmt.Sprintf("partial %q is defined more than once", key))
+			}
+
+			r.partials[key] = partials[key]
+		}
+	}
+
+	nodes, err := parseMustache(text, r.left, r.right)
+	if err != nil {
+		return "", false, function.NewArgumentFuncError(0, err.Error())
+	}
+
+	if err := r.render(nodes, []any{data}); errors.Is(err, errUnknown) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, function.NewFuncError(err.Error())
 	}
+
+	return r.b.String(), true, nil
 }
 
-func unwrap(d types.Dynamic) any {
-	switch d := d.UnderlyingValue().(type) {
-	case types.Bool:
-		return d.ValueBool()
-	case types.Number:
-		return d.ValueBigFloat()
-	case types.List:
-		return d.Elements()
-	case types.Map:
-		return d.Elements()
-	case types.Object:
-		return d.Attributes()
-	case types.Set:
-		return d.Elements()
-	case types.String:
-		return d.ValueString()
-	case types.Tuple:
-		return d.Elements()
-	default:
-		return nil
+func (f execute) ValidateParameterString(_ context.Context, req function.StringParameterValidatorRequest, resp *function.StringParameterValidatorResponse) {
+	if err := stat(req.Value.ValueString()); err != nil {
+		resp.Error = function.NewArgumentFuncError(req.ArgumentPosition, err.Error())
 	}
 }
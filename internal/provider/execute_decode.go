@@ -0,0 +1,140 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ function.Function                 = (*executeDecode)(nil)
+	_ function.StringParameterValidator = (*executeDecode)(nil)
+)
+
+// executeDecode executes a template and decodes its output, where format is
+// either "json" or "yaml".
+type executeDecode struct {
+	file   bool
+	format string
+	name   string
+}
+
+func (f executeDecode) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	templateParameter := function.StringParameter{
+		AllowNullValue: false,
+	}
+
+	if f.file {
+		templateParameter.Description = "The text template file"
+		templateParameter.Name = "file"
+		templateParameter.Validators = []function.StringParameterValidator{f}
+	} else {
+		templateParameter.Description = "The text template"
+		templateParameter.Name = "text"
+	}
+
+	format := strings.ToUpper(f.format)
+
+	resp.Definition = function.Definition{
+		Description: fmt.Sprintf("Executes a Go text/template from `%s` using the provided `data` and decodes the output as %s, in the style of `%sdecode`. "+
+			"Output that fails to decode is reported with the line and column at which decoding failed, along with the surrounding lines of output and, for Go templates, of the template text or action that wrote it. "+
+			"Options are the same as for `execute`.", templateParameter.GetName(), format, f.format),
+		Parameters: []function.Parameter{
+			templateParameter,
+			function.DynamicParameter{
+				AllowNullValue:     true,
+				AllowUnknownValues: true,
+				Description:        "The data passed to the template",
+				Name:               "data",
+			},
+		},
+		Return:            function.DynamicReturn{},
+		Summary:           fmt.Sprintf("Decodes the %s output of a Go text/template from `%s` using the provided `data`", format, templateParameter.GetName()),
+		VariadicParameter: optionsParameter,
+	}
+}
+
+func (f executeDecode) Metadata(_ context.Context, _ function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = f.name
+}
+
+func (f executeDecode) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var text string
+	var data types.Dynamic
+	var values []types.Dynamic
+
+	if err := req.Arguments.Get(ctx, &text, &data, &values); err != nil {
+		resp.Error = function.ConcatFuncErrors(err)
+		return
+	}
+
+	opts, entry, parent, ok, ferr := options(values)
+	if ferr != nil {
+		resp.Error = ferr
+		return
+	} else if !ok {
+		resp.Error = resp.Result.Set(ctx, types.DynamicUnknown())
+		return
+	}
+
+	v, err := opts.merge(unwrap(data))
+	if err != nil {
+		resp.Error = function.NewFuncError(err.Error())
+		return
+	}
+
+	var s string
+	var r *tracer
+
+	// Go templates are traced, so that errors can point at the part of the
+	// template that wrote the output that failed to decode.
+	if opts.engine == "mustache" {
+		s, ok, ferr = execute{file: f.file}.mustache(text, v, opts, len(values)-1)
+	} else if r, ok, ferr = (execute{file: f.file}).trace(ctx, text, v, opts, entry, parent); ok {
+		s = r.b.String()
+	}
+
+	if ferr != nil {
+		resp.Error = ferr
+		return
+	} else if !ok {
+		resp.Error = resp.Result.Set(ctx, types.DynamicUnknown())
+		return
+	}
+
+	var decoded any
+
+	if f.format == "yaml" {
+		decoded, err = parseYAML(s)
+	} else {
+		decoded, err = parseJSON(s)
+	}
+
+	var e *decodeError
+	if errors.As(err, &e) && r != nil {
+		e.trace(r)
+	}
+
+	if err != nil {
+		resp.Error = function.NewFuncError(err.Error())
+		return
+	}
+
+	result, err := wrap(decoded)
+	if err != nil {
+		resp.Error = function.NewFuncError(err.Error())
+		return
+	}
+
+	resp.Error = resp.Result.Set(ctx, types.DynamicValue(result))
+}
+
+func (f executeDecode) ValidateParameterString(_ context.Context, req function.StringParameterValidatorRequest, resp *function.StringParameterValidatorResponse) {
+	if err := stat(req.Value.ValueString()); err != nil {
+		resp.Error = function.NewArgumentFuncError(req.ArgumentPosition, err.Error())
+	}
+}
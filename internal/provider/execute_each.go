@@ -0,0 +1,134 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ function.Function = (*executeEach)(nil)
+)
+
+type executeEach struct {
+	name string
+}
+
+func (f executeEach) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Description: "Executes a Go text/template from `text` once for each element of `list`, returning a list of the rendered text. " +
+			"The template is parsed once, and each execution receives an object holding the element as `.item`, its index as `.index` and the `shared` data as `.shared`. " +
+			"Errors name the index of the element that failed. " +
+			"Options are the same as for `execute`, except that the `mustache` engine is not supported.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Description: "The text template",
+				Name:        "text",
+			},
+			function.DynamicParameter{
+				AllowUnknownValues: true,
+				Description:        "The list, set or tuple whose elements are passed to the template",
+				Name:               "list",
+			},
+			function.DynamicParameter{
+				AllowNullValue:     true,
+				AllowUnknownValues: true,
+				Description:        "The data shared by every execution of the template",
+				Name:               "shared",
+			},
+		},
+		Return: function.ListReturn{
+			ElementType: types.StringType,
+		},
+		Summary:           "Executes a Go text/template from `text` for each element of `list`",
+		VariadicParameter: optionsParameter,
+	}
+}
+
+func (f executeEach) Metadata(_ context.Context, _ function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = f.name
+}
+
+func (f executeEach) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var text string
+	var list types.Dynamic
+	var shared types.Dynamic
+	var values []types.Dynamic
+
+	if err := req.Arguments.Get(ctx, &text, &list, &shared, &values); err != nil {
+		resp.Error = function.ConcatFuncErrors(err)
+		return
+	}
+
+	opts, entry, parent, ok, ferr := options(values)
+	if ferr != nil {
+		resp.Error = shift(ferr)
+		return
+	} else if !ok || list.IsUnknown() || list.IsUnderlyingValueUnknown() {
+		resp.Error = resp.Result.Set(ctx, types.ListUnknown(types.StringType))
+		return
+	} else if opts.engine == "mustache" {
+		resp.Error = function.NewArgumentFuncError(int64(3+len(values)-1), fmt.Sprintf("option \"engine\" must be \"go\" for %s", f.name))
+		return
+	}
+
+	items, ok := unwrap(list).([]any)
+	if !ok {
+		resp.Error = function.NewArgumentFuncError(1, "list must be a list, set or tuple")
+		return
+	}
+
+	t, ferr := execute{}.parse(ctx, text, opts, entry, parent)
+	if ferr != nil {
+		resp.Error = shift(ferr)
+		return
+	}
+
+	// The shared data is unwrapped and merged with any layers once, and the
+	// template is guarded once if any element or the shared data is not yet
+	// known.
+	v, err := opts.merge(unwrap(shared))
+	if err != nil {
+		resp.Error = function.NewFuncError(err.Error())
+		return
+	}
+	var g guarder
+
+	if !known(items) || !known(v) {
+		g = guard(t)
+	}
+
+	elements := make([]attr.Value, len(items))
+
+	for i, item := range items {
+		data := map[string]any{
+			"index":  int64(i),
+			"item":   item,
+			"shared": v,
+		}
+
+		if s, ok, err := renderGuarded(ctx, t, data, g); err != nil {
+			resp.Error = function.NewFuncError(fmt.Sprintf("element %d: %s", i, err))
+			return
+		} else if ok {
+			elements[i] = types.StringValue(s)
+		} else {
+			elements[i] = types.StringUnknown()
+		}
+	}
+
+	resp.Error = resp.Result.Set(ctx, types.ListValueMust(types.StringType, elements))
+}
+
+// shift moves errors attributed to the options of execute onto the options of
+// execute_each, which follow the shared data rather than the data.
+func shift(ferr *function.FuncError) *function.FuncError {
+	if ferr.FunctionArgument != nil && *ferr.FunctionArgument >= 2 {
+		return function.NewArgumentFuncError(*ferr.FunctionArgument+1, ferr.Text)
+	}
+
+	return ferr
+}
@@ -0,0 +1,167 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ function.Function                 = (*executeEnvsubst)(nil)
+	_ function.StringParameterValidator = (*executeEnvsubst)(nil)
+)
+
+type executeEnvsubst struct {
+	file bool
+	name string
+}
+
+func (f executeEnvsubst) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	templateParameter := function.StringParameter{
+		AllowNullValue: false,
+		Validators: []function.StringParameterValidator{
+			f,
+		},
+	}
+
+	if f.file {
+		templateParameter.Description = "The file holding `${NAME}` placeholders"
+		templateParameter.Name = "file"
+	} else {
+		templateParameter.Description = "The text holding `${NAME}` placeholders"
+		templateParameter.Name = "text"
+	}
+
+	resp.Definition = function.Definition{
+		Description: fmt.Sprintf("Substitutes shell-style placeholders in `%s` with values from the provided `data`. "+
+			"`${NAME}` is replaced by the value of `NAME`, where dots select nested values such as `${server.port}`, "+
+			"`${NAME:-word}` by `word` when `NAME` is missing, null or empty, "+
+			"and `${NAME:?word}` fails with `word` as the message when `NAME` is missing, null or empty. "+
+			"Without the colon, `${NAME-word}` and `${NAME?word}` only treat missing and null values this way. "+
+			"`$${` is replaced by a literal `${`", templateParameter.GetName()),
+		Parameters: []function.Parameter{
+			templateParameter,
+			function.DynamicParameter{
+				AllowNullValue:     true,
+				AllowUnknownValues: true,
+				Description:        "The data used to resolve the placeholders",
+				Name:               "data",
+			},
+		},
+		Return:  function.StringReturn{},
+		Summary: fmt.Sprintf("Substitutes shell-style placeholders in `%s` with values from the provided `data`", templateParameter.GetName()),
+		VariadicParameter: function.DynamicParameter{
+			AllowUnknownValues: true,
+			Description: "Objects configuring the substitution, where later objects take precedence: " +
+				"`strict` fails on `${NAME}` placeholders whose value is missing or null, rather than replacing them with nothing",
+			Name: "options",
+		},
+	}
+}
+
+func (f executeEnvsubst) Metadata(_ context.Context, _ function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = f.name
+}
+
+func (f executeEnvsubst) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var text string
+	var data types.Dynamic
+	var values []types.Dynamic
+
+	if err := req.Arguments.Get(ctx, &text, &data, &values); err != nil {
+		resp.Error = function.ConcatFuncErrors(err)
+		return
+	}
+
+	var strict bool
+
+	for i, v := range values {
+		if !known(unwrap(v)) {
+			resp.Error = resp.Result.Set(ctx, types.StringUnknown())
+			return
+		} else if err := envsubstOptions(&strict, unwrap(v)); err != nil {
+			resp.Error = function.NewArgumentFuncError(int64(2+i), err.Error())
+			return
+		}
+	}
+
+	nodes, err := parseEnvsubstSource(f.file, text)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	r := envsubstRenderer{data: unwrap(data), strict: strict}
+
+	if err := r.render(nodes); errors.Is(err, errUnknown) {
+		resp.Error = resp.Result.Set(ctx, types.StringUnknown())
+		return
+	} else if err != nil {
+		resp.Error = function.NewFuncError(err.Error())
+		return
+	} else if err := resp.Result.Set(ctx, r.b.String()); err != nil {
+		resp.Error = err
+		return
+	}
+}
+
+func (f executeEnvsubst) ValidateParameterString(_ context.Context, req function.StringParameterValidatorRequest, resp *function.StringParameterValidatorResponse) {
+	v := req.Value.ValueString()
+
+	if f.file {
+		if err := stat(v); err != nil {
+			resp.Error = function.NewArgumentFuncError(req.ArgumentPosition, err.Error())
+			return
+		}
+	}
+
+	if _, err := parseEnvsubstSource(f.file, v); err != nil {
+		resp.Error = function.NewArgumentFuncError(req.ArgumentPosition, err.Error())
+	}
+}
+
+// parseEnvsubstSource parses the text, or the file named by text when file is
+// true.
+func parseEnvsubstSource(file bool, text string) ([]any, error) {
+	if !file {
+		return parseEnvsubst(text)
+	}
+
+	b, err := os.ReadFile(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseEnvsubst(string(b))
+}
+
+// envsubstOptions merges an options object into strict.
+func envsubstOptions(strict *bool, v any) error {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return errors.New("options must be an object")
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		if m[key] == nil {
+			continue
+		}
+
+		switch key {
+		case "strict":
+			if *strict, ok = m[key].(bool); !ok {
+				return fmt.Errorf("option %q must be a bool", key)
+			}
+		default:
+			return fmt.Errorf("unsupported option %q; expected strict", key)
+		}
+	}
+
+	return nil
+}
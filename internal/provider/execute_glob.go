@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ function.Function                 = (*executeGlob)(nil)
+	_ function.StringParameterValidator = (*executeGlob)(nil)
+)
+
+type executeGlob struct {
+	name string
+}
+
+func (f executeGlob) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Description: "Executes the Go text/template named `template` from the set of files matching `pattern` using the provided `data`. " +
+			"Each file is named after its path relative to the directory preceding the first wildcard in `pattern`, so that files can reference partials defined in, or named after, their siblings.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Description: "The glob pattern matching the text template files, where `**` matches zero or more directories",
+				Name:        "pattern",
+				Validators: []function.StringParameterValidator{
+					f,
+				},
+			},
+			function.StringParameter{
+				Description: "The name of the template to execute",
+				Name:        "template",
+			},
+			function.DynamicParameter{
+				AllowNullValue:     true,
+				AllowUnknownValues: true,
+				Description:        "The data passed to the template",
+				Name:               "data",
+			},
+		},
+		Return:  function.StringReturn{},
+		Summary: "Executes a Go text/template from the files matching `pattern` using the provided `data`",
+	}
+}
+
+func (f executeGlob) Metadata(_ context.Context, _ function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = f.name
+}
+
+func (f executeGlob) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var pattern string
+	var name string
+	var data types.Dynamic
+
+	if err := req.Arguments.Get(ctx, &pattern, &name, &data); err != nil {
+		resp.Error = function.ConcatFuncErrors(err)
+		return
+	}
+
+	t, err := parseGlob(ctx, pattern)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	entry, err := lookup(t, name)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(1, err.Error())
+		return
+	}
+
+	if s, ok, err := render(ctx, entry, data); err != nil {
+		resp.Error = function.NewFuncError(err.Error())
+		return
+	} else if !ok {
+		resp.Error = resp.Result.Set(ctx, types.StringUnknown())
+		return
+	} else if err := resp.Result.Set(ctx, s); err != nil {
+		resp.Error = err
+		return
+	}
+}
+
+func (f executeGlob) ValidateParameterString(ctx context.Context, req function.StringParameterValidatorRequest, resp *function.StringParameterValidatorResponse) {
+	if _, err := parseGlob(ctx, req.Value.ValueString()); err != nil {
+		resp.Error = function.NewArgumentFuncError(req.ArgumentPosition, err.Error())
+	}
+}
@@ -0,0 +1,210 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template/parse"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ function.Function                 = (*executeHTML)(nil)
+	_ function.StringParameterValidator = (*executeHTML)(nil)
+)
+
+type executeHTML struct {
+	file bool
+	name string
+}
+
+func (f executeHTML) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	templateParameter := function.StringParameter{
+		AllowNullValue: false,
+		Validators: []function.StringParameterValidator{
+			f,
+		},
+	}
+
+	if f.file {
+		templateParameter.Description = "The HTML template file"
+		templateParameter.Name = "file"
+	} else {
+		templateParameter.Description = "The HTML template"
+		templateParameter.Name = "text"
+	}
+
+	resp.Definition = function.Definition{
+		Description: fmt.Sprintf("Executes a Go html/template from `%s` using the provided `data`. "+
+			"Values from `data` are escaped according to the HTML, CSS, JavaScript or URL context in which they appear. "+
+			"The output of `include` and of functions declared with `{{ define \"func:name\" }}` is the escaped HTML of their template, and is not escaped again, while `tpl` is not supported.", templateParameter.GetName()),
+		Parameters: []function.Parameter{
+			templateParameter,
+			function.DynamicParameter{
+				AllowNullValue:     true,
+				AllowUnknownValues: true,
+				Description:        "The data passed to the template",
+				Name:               "data",
+			},
+		},
+		Return:  function.StringReturn{},
+		Summary: fmt.Sprintf("Executes a Go html/template from `%s` using the provided `data`", templateParameter.GetName()),
+	}
+}
+
+func (f executeHTML) Metadata(_ context.Context, _ function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = f.name
+}
+
+func (f executeHTML) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var text string
+	var data types.Dynamic
+
+	if err := req.Arguments.Get(ctx, &text, &data); err != nil {
+		resp.Error = function.ConcatFuncErrors(err)
+		return
+	}
+
+	t, err := parseHTML(ctx, f.file, text)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	if s, ok, err := renderHTML(t, data); errors.As(err, new(*template.Error)) {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	} else if err != nil {
+		resp.Error = function.NewFuncError(err.Error())
+		return
+	} else if !ok {
+		resp.Error = resp.Result.Set(ctx, types.StringUnknown())
+		return
+	} else if err := resp.Result.Set(ctx, s); err != nil {
+		resp.Error = err
+		return
+	}
+}
+
+func (f executeHTML) ValidateParameterString(ctx context.Context, req function.StringParameterValidatorRequest, resp *function.StringParameterValidatorResponse) {
+	v := req.Value.ValueString()
+
+	if f.file {
+		if err := stat(v); err != nil {
+			resp.Error = function.NewArgumentFuncError(req.ArgumentPosition, err.Error())
+			return
+		}
+	}
+
+	if _, err := parseHTML(ctx, f.file, v); err != nil {
+		resp.Error = function.NewArgumentFuncError(req.ArgumentPosition, err.Error())
+	}
+}
+
+// parseHTML parses the HTML template text, or the HTML template file named by
+// text when file is true, with the provider function library and the
+// functions that the template declares.
+func parseHTML(ctx context.Context, file bool, text string) (*template.Template, error) {
+	if !file {
+		return template.New("").Funcs(functions(ctx)).Funcs(template.FuncMap(definedFuncs(text))).Parse(text)
+	}
+
+	b, err := os.ReadFile(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return template.New(filepath.Base(text)).Funcs(functions(ctx)).Funcs(template.FuncMap(definedFuncs(string(b)))).Parse(string(b))
+}
+
+// renderHTML executes the HTML template with the unwrapped data, reporting
+// false if the template read a value that is not known until apply. Escaping
+// errors are reported as *template.Error.
+func renderHTML(t *template.Template, data attr.Value) (string, bool, error) {
+	v := unwrap(data)
+
+	// The parse trees must be guarded before the first execution, which
+	// rewrites them to escape each action.
+	var g guarder
+
+	if !known(v) {
+		g = guarder{texts: map[parse.Node]guardedText{}}
+
+		t.Funcs(template.FuncMap(guards))
+
+		for _, t := range t.Templates() {
+			g.guardTree(t.Tree)
+		}
+	}
+
+	composeHTML(t)
+
+	b := strings.Builder{}
+	if err := t.Execute(&b, v); errors.Is(err, errUnknown) {
+		return "", false, nil
+	} else if err != nil {
+		return "", true, g.restore(err)
+	}
+
+	return b.String(), true, nil
+}
+
+// composeHTML binds include to the templates associated with t, along with a
+// function for each template named "func:name", in the style of compose. The
+// output of these templates has already been escaped, so it is returned as
+// template.HTML to avoid escaping it twice. HTML templates cannot be cloned
+// once executed, so tpl fails instead.
+func composeHTML(t *template.Template) {
+	var depth int
+
+	execute := func(call string, t *template.Template, data any) (template.HTML, error) {
+		if depth == includeDepth {
+			return "", &depthError{call: call}
+		}
+
+		depth++
+		defer func() { depth-- }()
+
+		var d *depthError
+
+		b := strings.Builder{}
+		if err := t.Execute(&b, data); errors.As(err, &d) {
+			return "", d
+		} else if err != nil {
+			return "", err
+		}
+
+		return template.HTML(b.String()), nil
+	}
+
+	m := template.FuncMap{}
+
+	for _, d := range t.Templates() {
+		if name, ok := strings.CutPrefix(d.Name(), "func:"); ok && funcName.MatchString(name) {
+			m[name] = func(args ...any) (template.HTML, error) {
+				return execute(fmt.Sprintf("function %q", name), d, map[string]any{"args": args})
+			}
+		}
+	}
+
+	m["include"] = func(name string, data any) (template.HTML, error) {
+		if entry := t.Lookup(name); entry == nil {
+			return "", fmt.Errorf("template %q is not defined", name)
+		} else {
+			return execute(fmt.Sprintf("include %q", name), entry, data)
+		}
+	}
+
+	m["tpl"] = func(_ string, _ any) (template.HTML, error) {
+		return "", errors.New("tpl is not supported by execute_html")
+	}
+
+	t.Funcs(m)
+}
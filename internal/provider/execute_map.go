@@ -0,0 +1,132 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"text/template"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ function.Function = (*executeMap)(nil)
+)
+
+type executeMap struct {
+	name string
+}
+
+func (f executeMap) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Description: "Executes each Go text/template in `templates` using the same `data`, returning a map from each key to its rendered text. " +
+			"Every template is parsed before any is executed, and errors name the key of the template that failed. " +
+			"Options are the same as for `execute` and apply to every template.",
+		Parameters: []function.Parameter{
+			function.MapParameter{
+				Description: "The text templates, keyed by name",
+				ElementType: types.StringType,
+				Name:        "templates",
+			},
+			function.DynamicParameter{
+				AllowNullValue:     true,
+				AllowUnknownValues: true,
+				Description:        "The data passed to each template",
+				Name:               "data",
+			},
+		},
+		Return: function.MapReturn{
+			ElementType: types.StringType,
+		},
+		Summary:           "Executes a map of Go text/templates using the provided `data`",
+		VariadicParameter: optionsParameter,
+	}
+}
+
+func (f executeMap) Metadata(_ context.Context, _ function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = f.name
+}
+
+func (f executeMap) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var templates map[string]string
+	var data types.Dynamic
+	var values []types.Dynamic
+
+	if err := req.Arguments.Get(ctx, &templates, &data, &values); err != nil {
+		resp.Error = function.ConcatFuncErrors(err)
+		return
+	}
+
+	opts, entry, parent, ok, ferr := options(values)
+	if ferr != nil {
+		resp.Error = ferr
+		return
+	} else if !ok {
+		resp.Error = resp.Result.Set(ctx, types.MapUnknown(types.StringType))
+		return
+	}
+
+	keys := slices.Sorted(maps.Keys(templates))
+	parsed := make(map[string]*template.Template, len(keys))
+
+	for _, key := range keys {
+		if opts.engine == "mustache" {
+			left, right := opts.delims()
+
+			if _, err := parseMustache(templates[key], left, right); err != nil {
+				resp.Error = function.NewArgumentFuncError(0, fmt.Sprintf("template %q: %s", key, err))
+				return
+			}
+		} else if t, ferr := (execute{}).parse(ctx, templates[key], opts, entry, parent); ferr != nil {
+			resp.Error = keyed(key, ferr)
+			return
+		} else {
+			parsed[key] = t
+		}
+	}
+
+	// The data is unwrapped and merged once and shared by every template.
+	v, err := opts.merge(unwrap(data))
+	if err != nil {
+		resp.Error = function.NewFuncError(err.Error())
+		return
+	}
+
+	elements := make(map[string]attr.Value, len(keys))
+
+	for _, key := range keys {
+		var s string
+		var ok bool
+
+		if opts.engine == "mustache" {
+			s, ok, ferr = (execute{}).mustache(templates[key], v, opts, len(values)-1)
+		} else if s, ok, err = renderUnwrapped(ctx, parsed[key], v); err != nil {
+			ferr = function.NewFuncError(err.Error())
+		}
+
+		if ferr != nil {
+			resp.Error = keyed(key, ferr)
+			return
+		} else if ok {
+			elements[key] = types.StringValue(s)
+		} else {
+			elements[key] = types.StringUnknown()
+		}
+	}
+
+	resp.Error = resp.Result.Set(ctx, types.MapValueMust(types.StringType, elements))
+}
+
+// keyed attributes an error to the template with the given key.
+func keyed(key string, ferr *function.FuncError) *function.FuncError {
+	text := fmt.Sprintf("template %q: %s", key, ferr.Text)
+
+	if ferr.FunctionArgument != nil {
+		return function.NewArgumentFuncError(*ferr.FunctionArgument, text)
+	}
+
+	return function.NewFuncError(text)
+}
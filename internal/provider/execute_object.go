@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ function.Function                 = (*executeObject)(nil)
+	_ function.StringParameterValidator = (*executeObject)(nil)
+)
+
+type executeObject struct {
+	file bool
+	name string
+}
+
+func (f executeObject) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	templateParameter := function.StringParameter{
+		AllowNullValue: false,
+	}
+
+	if f.file {
+		templateParameter.Description = "The text template file"
+		templateParameter.Name = "file"
+		templateParameter.Validators = []function.StringParameterValidator{f}
+	} else {
+		templateParameter.Description = "The text template"
+		templateParameter.Name = "text"
+	}
+
+	resp.Definition = function.Definition{
+		Description: fmt.Sprintf("Executes a Go text/template from `%s` using the provided `data`, returning the object built by the template instead of its text. "+
+			"The template sets values with `{{ emit \"path.to.key\" value }}` and adds them to lists with `{{ append \"path.to.list\" value }}`, creating the objects along the path, and any text it prints is ignored. "+
+			"Options are the same as for `execute`, except that the `mustache` engine is not supported.", templateParameter.GetName()),
+		Parameters: []function.Parameter{
+			templateParameter,
+			function.DynamicParameter{
+				AllowNullValue:     true,
+				AllowUnknownValues: true,
+				Description:        "The data passed to the template",
+				Name:               "data",
+			},
+		},
+		Return:            function.DynamicReturn{},
+		Summary:           fmt.Sprintf("Builds an object from a Go text/template from `%s` using the provided `data`", templateParameter.GetName()),
+		VariadicParameter: optionsParameter,
+	}
+}
+
+func (f executeObject) Metadata(_ context.Context, _ function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = f.name
+}
+
+func (f executeObject) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var text string
+	var data types.Dynamic
+	var values []types.Dynamic
+
+	if err := req.Arguments.Get(ctx, &text, &data, &values); err != nil {
+		resp.Error = function.ConcatFuncErrors(err)
+		return
+	}
+
+	opts, entry, parent, ok, ferr := options(values)
+	if ferr != nil {
+		resp.Error = ferr
+		return
+	} else if !ok {
+		resp.Error = resp.Result.Set(ctx, types.DynamicUnknown())
+		return
+	} else if opts.engine == "mustache" {
+		resp.Error = function.NewArgumentFuncError(int64(2+len(values)-1), fmt.Sprintf("option \"engine\" must be \"go\" for %s", f.name))
+		return
+	}
+
+	t, ferr := execute{file: f.file}.parse(ctx, text, opts, entry, parent)
+	if ferr != nil {
+		resp.Error = ferr
+		return
+	}
+
+	v, err := opts.merge(unwrap(data))
+	if err != nil {
+		resp.Error = function.NewFuncError(err.Error())
+		return
+	}
+
+	o := object{root: map[string]any{}}
+	t.Funcs(o.funcs())
+
+	if _, ok, err := renderUnwrapped(ctx, t, v); err != nil {
+		resp.Error = function.NewFuncError(err.Error())
+		return
+	} else if !ok {
+		resp.Error = resp.Result.Set(ctx, types.DynamicUnknown())
+		return
+	}
+
+	result, err := wrap(o.root)
+	if err != nil {
+		resp.Error = function.NewFuncError(err.Error())
+		return
+	}
+
+	resp.Error = resp.Result.Set(ctx, types.DynamicValue(result))
+}
+
+func (f executeObject) ValidateParameterString(_ context.Context, req function.StringParameterValidatorRequest, resp *function.StringParameterValidatorResponse) {
+	if err := stat(req.Value.ValueString()); err != nil {
+		resp.Error = function.NewArgumentFuncError(req.ArgumentPosition, err.Error())
+	}
+}
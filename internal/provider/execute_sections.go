@@ -0,0 +1,135 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"go.austindrenski.io/gotter/templates"
+)
+
+var (
+	_ function.Function                 = (*executeSections)(nil)
+	_ function.StringParameterValidator = (*executeSections)(nil)
+)
+
+type executeSections struct {
+	file bool
+	name string
+}
+
+func (f executeSections) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	templateParameter := function.StringParameter{
+		AllowNullValue: false,
+	}
+
+	if f.file {
+		templateParameter.Description = "The text template file"
+		templateParameter.Name = "file"
+		templateParameter.Validators = []function.StringParameterValidator{f}
+	} else {
+		templateParameter.Description = "The text template"
+		templateParameter.Name = "text"
+	}
+
+	resp.Definition = function.Definition{
+		Description: fmt.Sprintf("Executes a Go text/template from `%s` using the provided `data`, returning a map from the name of each section the template declares to the text it rendered. "+
+			"Sections are declared with `{{ section \"name\" }}...{{ end }}`, may be nested, and see the variables of the enclosing template, while text outside of every section is ignored. "+
+			"Options are the same as for `execute`, except that the `mustache` engine is not supported.", templateParameter.GetName()),
+		Parameters: []function.Parameter{
+			templateParameter,
+			function.DynamicParameter{
+				AllowNullValue:     true,
+				AllowUnknownValues: true,
+				Description:        "The data passed to the template",
+				Name:               "data",
+			},
+		},
+		Return: function.MapReturn{
+			ElementType: types.StringType,
+		},
+		Summary:           fmt.Sprintf("Executes the sections of a Go text/template from `%s` using the provided `data`", templateParameter.GetName()),
+		VariadicParameter: optionsParameter,
+	}
+}
+
+func (f executeSections) Metadata(_ context.Context, _ function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = f.name
+}
+
+func (f executeSections) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var text string
+	var data types.Dynamic
+	var values []types.Dynamic
+
+	if err := req.Arguments.Get(ctx, &text, &data, &values); err != nil {
+		resp.Error = function.ConcatFuncErrors(err)
+		return
+	}
+
+	opts, entry, parent, ok, ferr := options(values)
+	if ferr != nil {
+		resp.Error = ferr
+		return
+	} else if !ok {
+		resp.Error = resp.Result.Set(ctx, types.MapUnknown(types.StringType))
+		return
+	} else if opts.engine == "mustache" {
+		resp.Error = function.NewArgumentFuncError(int64(2+len(values)-1), fmt.Sprintf("option \"engine\" must be \"go\" for %s", f.name))
+		return
+	}
+
+	opts.sections = true
+
+	t, ferr := execute{file: f.file}.parse(ctx, text, opts, entry, parent)
+	if ferr != nil {
+		resp.Error = ferr
+		return
+	} else if err := closeSections(t); err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	v, err := opts.merge(unwrap(data))
+	if err != nil {
+		resp.Error = function.NewFuncError(err.Error())
+		return
+	}
+
+	var g guarder
+
+	if !known(v) {
+		g = guard(t)
+	}
+
+	// The recorder shares the depth of include and tpl, so that sections can
+	// only be declared by templates that write directly to the output.
+	r := sectionRecorder{depth: new(int), texts: map[string]string{}}
+
+	compose(t, g, r.depth)
+	t.Funcs(r.funcs())
+
+	if err := templates.Execute(ctx, t, v, &r.b); errors.Is(err, errUnknown) {
+		resp.Error = resp.Result.Set(ctx, types.MapUnknown(types.StringType))
+		return
+	} else if err != nil {
+		resp.Error = function.NewFuncError(g.restore(err).Error())
+		return
+	}
+
+	elements := make(map[string]attr.Value, len(r.texts))
+	for name, s := range r.texts {
+		elements[name] = types.StringValue(s)
+	}
+
+	resp.Error = resp.Result.Set(ctx, types.MapValueMust(types.StringType, elements))
+}
+
+func (f executeSections) ValidateParameterString(_ context.Context, req function.StringParameterValidatorRequest, resp *function.StringParameterValidatorResponse) {
+	if err := stat(req.Value.ValueString()); err != nil {
+		resp.Error = function.NewArgumentFuncError(req.ArgumentPosition, err.Error())
+	}
+}
@@ -0,0 +1,339 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ resource.Resource                   = (*fileResource)(nil)
+	_ resource.ResourceWithImportState    = (*fileResource)(nil)
+	_ resource.ResourceWithModifyPlan     = (*fileResource)(nil)
+	_ resource.ResourceWithValidateConfig = (*fileResource)(nil)
+)
+
+// writtenKey is the private state key holding the checksum of the content
+// last written by the provider, used to detect out-of-band edits.
+const writtenKey = "sha256"
+
+type fileResource struct{}
+
+type fileResourceModel struct {
+	Base64Sha256        types.String  `tfsdk:"base64sha256"`
+	Data                types.Dynamic `tfsdk:"data"`
+	DirectoryPermission types.String  `tfsdk:"directory_permission"`
+	File                types.String  `tfsdk:"file"`
+	FilePermission      types.String  `tfsdk:"file_permission"`
+	Force               types.Bool    `tfsdk:"force"`
+	Options             types.Dynamic `tfsdk:"options"`
+	Path                types.String  `tfsdk:"path"`
+	Sha256              types.String  `tfsdk:"sha256"`
+	Text                types.String  `tfsdk:"text"`
+}
+
+// privateState is implemented by the private state of each request and
+// response.
+type privateState interface {
+	GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics)
+	SetKey(ctx context.Context, key string, value []byte) diag.Diagnostics
+}
+
+func (r fileResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var model fileResourceModel
+
+	if resp.Diagnostics.Append(req.Plan.Get(ctx, &model)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	if resp.Diagnostics.Append(r.write(ctx, &model, "")...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.setWritten(ctx, resp.Private, model.Sha256.ValueString())...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
+}
+
+func (r fileResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var model fileResourceModel
+
+	if resp.Diagnostics.Append(req.State.Get(ctx, &model)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := os.Remove(model.Path.ValueString()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		resp.Diagnostics.AddAttributeError(path.Root("path"), "Failed to delete file", err.Error())
+	}
+}
+
+func (r fileResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("path"), req, resp)
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("directory_permission"), "0755")...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("force"), false)...)
+}
+
+func (r fileResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_file"
+}
+
+func (r fileResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var model fileResourceModel
+
+	if resp.Diagnostics.Append(req.Plan.Get(ctx, &model)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	if model.Text.IsUnknown() || model.File.IsUnknown() {
+		return
+	}
+
+	s, ok, diags := renderSource(ctx, model.Text, model.File, model.Data, model.Options)
+	if resp.Diagnostics.Append(diags...); resp.Diagnostics.HasError() {
+		return
+	} else if !ok {
+		model.Base64Sha256 = types.StringUnknown()
+		model.Sha256 = types.StringUnknown()
+	} else {
+		sha256, base64sha256 := checksums([]byte(s))
+		model.Base64Sha256 = types.StringValue(base64sha256)
+		model.Sha256 = types.StringValue(sha256)
+	}
+
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &model)...)
+}
+
+func (r fileResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var model fileResourceModel
+
+	if resp.Diagnostics.Append(req.State.Get(ctx, &model)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	b, err := os.ReadFile(model.Path.ValueString())
+	if errors.Is(err, fs.ErrNotExist) {
+		resp.State.RemoveResource(ctx)
+		return
+	} else if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("path"), "Failed to read file", err.Error())
+		return
+	}
+
+	stat, err := os.Stat(model.Path.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("path"), "Failed to read file", err.Error())
+		return
+	}
+
+	sha256, base64sha256 := checksums(b)
+
+	model.Base64Sha256 = types.StringValue(base64sha256)
+	model.Sha256 = types.StringValue(sha256)
+
+	// The permission in state is kept as written when it matches the file, e.g.
+	// "644" for a file with mode 0644, so that configurations that omit the
+	// leading zero do not plan a change on every run.
+	if perm, err := permission(model.FilePermission.ValueString()); err != nil || perm != stat.Mode().Perm() {
+		model.FilePermission = types.StringValue(fmt.Sprintf("%04o", stat.Mode().Perm()))
+	}
+
+	written, diags := r.written(ctx, req.Private)
+	if resp.Diagnostics.Append(diags...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Files adopted through import are treated as if the provider wrote them.
+	if written == "" {
+		resp.Diagnostics.Append(r.setWritten(ctx, resp.Private, sha256)...)
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
+}
+
+func (r fileResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"base64sha256": schema.StringAttribute{
+				Computed:    true,
+				Description: "The base64-encoded SHA256 checksum of the rendered file",
+			},
+			"data": schema.DynamicAttribute{
+				Description: "The data passed to the template",
+				Optional:    true,
+			},
+			"directory_permission": schema.StringAttribute{
+				Computed:    true,
+				Default:     stringdefault.StaticString("0755"),
+				Description: "The permissions, in octal, of any parent directories created for the file",
+				Optional:    true,
+			},
+			"file": schema.StringAttribute{
+				Description: "The text template file. Conflicts with `text`.",
+				Optional:    true,
+			},
+			"file_permission": schema.StringAttribute{
+				Computed:    true,
+				Default:     stringdefault.StaticString("0644"),
+				Description: "The permissions, in octal, of the rendered file",
+				Optional:    true,
+			},
+			"force": schema.BoolAttribute{
+				Computed:    true,
+				Default:     booldefault.StaticBool(false),
+				Description: "Overwrite the file even if it was modified outside of Terraform",
+				Optional:    true,
+			},
+			"options": schema.DynamicAttribute{
+				Description: "An object configuring the template, with the same keys as the options of the `execute` function",
+				Optional:    true,
+			},
+			"path": schema.StringAttribute{
+				Description: "The path of the rendered file",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Required: true,
+			},
+			"sha256": schema.StringAttribute{
+				Computed:    true,
+				Description: "The hex-encoded SHA256 checksum of the rendered file",
+			},
+			"text": schema.StringAttribute{
+				Description: "The text template. Conflicts with `file`.",
+				Optional:    true,
+			},
+		},
+		Description: "Renders a Go text/template from `text` or `file` using the provided `data` and `options` to the file at `path`",
+	}
+}
+
+func (r fileResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var model fileResourceModel
+
+	if resp.Diagnostics.Append(req.Plan.Get(ctx, &model)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	written, diags := r.written(ctx, req.Private)
+	if resp.Diagnostics.Append(diags...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	if resp.Diagnostics.Append(r.write(ctx, &model, written)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.setWritten(ctx, resp.Private, model.Sha256.ValueString())...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
+}
+
+func (r fileResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var model fileResourceModel
+
+	if resp.Diagnostics.Append(req.Config.Get(ctx, &model)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(validateSource(model.Text, model.File)...)
+
+	for name, v := range map[string]types.String{
+		"directory_permission": model.DirectoryPermission,
+		"file_permission":      model.FilePermission,
+	} {
+		if v.IsNull() || v.IsUnknown() {
+			continue
+		}
+
+		if _, err := permission(v.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root(name), "Invalid file permission", err.Error())
+		}
+	}
+}
+
+// write renders the template to the file, refusing to replace content that
+// differs from written unless forced. An empty written checksum means the
+// provider has not written the file before.
+func (r fileResource) write(ctx context.Context, model *fileResourceModel, written string) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	s, _, d := renderSource(ctx, model.Text, model.File, model.Data, model.Options)
+	if diags.Append(d...); diags.HasError() {
+		return diags
+	}
+
+	sha256, base64sha256 := checksums([]byte(s))
+
+	if b, err := os.ReadFile(model.Path.ValueString()); err == nil && !model.Force.ValueBool() {
+		if current, _ := checksums(b); current != sha256 && current != written {
+			diags.AddAttributeError(
+				path.Root("path"),
+				"File modified outside of Terraform",
+				fmt.Sprintf("%q has been modified outside of Terraform. Set `force = true` to overwrite it.", model.Path.ValueString()))
+			return diags
+		}
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		diags.AddAttributeError(path.Root("path"), "Failed to read file", err.Error())
+		return diags
+	}
+
+	perm, err := permission(model.FilePermission.ValueString())
+	if err != nil {
+		diags.AddAttributeError(path.Root("file_permission"), "Invalid file permission", err.Error())
+		return diags
+	}
+
+	dirPerm, err := permission(model.DirectoryPermission.ValueString())
+	if err != nil {
+		diags.AddAttributeError(path.Root("directory_permission"), "Invalid file permission", err.Error())
+		return diags
+	}
+
+	if err := write(model.Path.ValueString(), []byte(s), perm, dirPerm); err != nil {
+		diags.AddAttributeError(path.Root("path"), "Failed to write file", err.Error())
+		return diags
+	}
+
+	model.Base64Sha256 = types.StringValue(base64sha256)
+	model.Sha256 = types.StringValue(sha256)
+
+	return diags
+}
+
+// written returns the checksum of the content last written by the provider.
+func (r fileResource) written(ctx context.Context, private privateState) (string, diag.Diagnostics) {
+	var written string
+
+	b, diags := private.GetKey(ctx, writtenKey)
+	if diags.HasError() || len(b) == 0 {
+		return "", diags
+	}
+
+	if err := json.Unmarshal(b, &written); err != nil {
+		diags.AddError("Failed to read private state", err.Error())
+	}
+
+	return written, diags
+}
+
+// setWritten records the checksum of the content written by the provider.
+func (r fileResource) setWritten(ctx context.Context, private privateState, sha256 string) diag.Diagnostics {
+	b, _ := json.Marshal(sha256)
+	return private.SetKey(ctx, writtenKey, b)
+}
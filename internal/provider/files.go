@@ -0,0 +1,54 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// permission parses an octal file mode such as "0644". The setuid, setgid and
+// sticky bits are rejected, since os.FileMode holds them elsewhere.
+func permission(s string) (os.FileMode, error) {
+	if m, err := strconv.ParseUint(s, 8, 32); err != nil {
+		return 0, fmt.Errorf("%q is not a valid octal file mode", s)
+	} else if m > 0o7777 {
+		return 0, fmt.Errorf("%q is not a valid octal file mode", s)
+	} else if m > 0o777 {
+		return 0, fmt.Errorf("%q sets the setuid, setgid or sticky bits, which are not supported", s)
+	} else {
+		return os.FileMode(m), nil
+	}
+}
+
+// write atomically replaces the file with the content, creating any missing
+// parent directories along the way.
+func write(file string, content []byte, perm os.FileMode, dirPerm os.FileMode) error {
+	dir := filepath.Dir(file)
+
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(content); err != nil {
+		_ = f.Close()
+		return err
+	} else if err := f.Chmod(perm); err != nil {
+		_ = f.Close()
+		return err
+	} else if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	} else if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), file)
+}
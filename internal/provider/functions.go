@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"context"
+	"maps"
+	"text/template"
+
+	"go.austindrenski.io/gotter/templates"
+)
+
+// functions extends the gotter function library with the functions needed to
+// work with Terraform values, including numeric comparisons and arithmetic
+// that accept any combination of int64, float64 and arbitrary precision
+// numbers, with include and tpl, which render templates to strings, with emit
+// and append, which build the result of execute_object, and with section,
+// which declares the sections of execute_sections.
+func functions(ctx context.Context) template.FuncMap {
+	m := templates.Functions(ctx)
+
+	maps.Copy(m, template.FuncMap{
+		"add":    add,
+		"div":    div,
+		"eq":     eq,
+		"ge":     ge,
+		"gt":     gt,
+		"indent": indent,
+		"le":     le,
+		"lt":     lt,
+		"mod":    mod,
+		"mul":    mul,
+		"ne":     ne,
+		"sub":    sub,
+	})
+
+	// The gotter functions that take an int are adapted to accept any integer,
+	// see count.
+	if f, ok := m["split_n"].(func(string, int, string) ([]string, error)); ok {
+		m["split_n"] = func(pattern string, n any, source string) ([]string, error) {
+			i, err := count(n)
+			if err != nil {
+				return nil, err
+			}
+
+			return f(pattern, i, source)
+		}
+	}
+
+	if f, ok := m["truncate"].(func(int, string) string); ok {
+		m["truncate"] = func(n any, source string) (string, error) {
+			i, err := count(n)
+			if err != nil {
+				return "", err
+			}
+
+			return f(i, source), nil
+		}
+	}
+
+	maps.Copy(m, emitters)
+	maps.Copy(m, includes)
+	maps.Copy(m, sectioners)
+
+	return m
+}
@@ -0,0 +1,174 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// includeDepth limits the nesting of include and tpl, so that a template that
+// unconditionally includes itself fails instead of recursing forever.
+const includeDepth = 100
+
+// depthError reports a call to include or tpl that exceeds includeDepth.
+type depthError struct {
+	call string
+}
+
+func (e *depthError) Error() string {
+	return fmt.Sprintf("%s exceeds the maximum depth of %d", e.call, includeDepth)
+}
+
+// includes holds placeholders for the functions that compose binds to a set of
+// templates, so that templates using them can be parsed before they are bound.
+var includes = template.FuncMap{
+	"include": func(name string, _ any) (string, error) {
+		return "", fmt.Errorf("include %q is not available in this template", name)
+	},
+	"tpl": func(_ string, _ any) (string, error) {
+		return "", fmt.Errorf("tpl is not available in this template")
+	},
+}
+
+// definedFunc matches the definitions of templates that declare functions,
+// e.g. {{ define "func:fqdn" }}, capturing the name of the function.
+var definedFunc = regexp.MustCompile("define\\s+[\"`]func:([A-Za-z][A-Za-z0-9_]*)[\"`]")
+
+// funcName matches the names of functions that templates can declare.
+var funcName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// definedFuncs returns a placeholder for each function declared by the texts,
+// so that templates calling them can be parsed before compose binds them.
+func definedFuncs(texts ...string) template.FuncMap {
+	m := template.FuncMap{}
+
+	for _, text := range texts {
+		for _, match := range definedFunc.FindAllStringSubmatch(text, -1) {
+			m[match[1]] = func(...any) (string, error) {
+				return "", fmt.Errorf("function %q is not available in this template", match[1])
+			}
+		}
+	}
+
+	return m
+}
+
+// withDefinedFuncs registers the functions declared by the texts, in the
+// style of templates.WithFuncs, see definedFuncs.
+func withDefinedFuncs(texts ...string) func(context.Context, *template.Template) *template.Template {
+	return func(_ context.Context, t *template.Template) *template.Template {
+		return t.Funcs(definedFuncs(texts...))
+	}
+}
+
+// compose binds include and tpl to the templates associated with t, along
+// with a function for each template named "func:name", which executes the
+// template with its arguments as $.args. The depth counts the calls that are
+// currently executing. Templates parsed by tpl are guarded against unknown
+// values by g, see guard.
+func compose(t *template.Template, g guarder, depth *int) {
+	enter := func(call string) error {
+		if *depth == includeDepth {
+			return &depthError{call: call}
+		}
+
+		*depth++
+
+		return nil
+	}
+
+	// Errors from nested executions are wrapped once per level, so the depth
+	// error is passed up on its own rather than nested a hundred times.
+	execute := func(t *template.Template, data any) (string, error) {
+		defer func() { *depth-- }()
+
+		var d *depthError
+
+		b := strings.Builder{}
+		if err := t.Execute(&b, data); errors.As(err, &d) {
+			return "", d
+		} else if err != nil {
+			return "", err
+		}
+
+		return b.String(), nil
+	}
+
+	m := template.FuncMap{}
+
+	for _, d := range t.Templates() {
+		if name, ok := strings.CutPrefix(d.Name(), "func:"); ok && funcName.MatchString(name) {
+			m[name] = func(args ...any) (string, error) {
+				if err := enter(fmt.Sprintf("function %q", name)); err != nil {
+					return "", err
+				}
+
+				return execute(d, map[string]any{"args": args})
+			}
+		}
+	}
+
+	t.Funcs(m)
+
+	t.Funcs(template.FuncMap{
+		"include": func(name string, data any) (string, error) {
+			entry, err := lookup(t, name)
+			if err != nil {
+				return "", err
+			} else if err := enter(fmt.Sprintf("include %q", name)); err != nil {
+				return "", err
+			}
+
+			return execute(entry, data)
+		},
+		"tpl": func(text string, data any) (string, error) {
+			c, err := t.Clone()
+			if err != nil {
+				return "", err
+			}
+
+			trees := map[*parse.Tree]bool{}
+			for _, t := range c.Templates() {
+				trees[t.Tree] = true
+			}
+
+			entry, err := c.New("tpl").Funcs(definedFuncs(text)).Parse(text)
+			if err != nil {
+				return "", err
+			}
+
+			for _, t := range c.Templates() {
+				if !trees[t.Tree] {
+					g.guardTree(t.Tree)
+				}
+			}
+
+			compose(c, g, depth)
+
+			if err := enter("tpl"); err != nil {
+				return "", err
+			}
+
+			return execute(entry, data)
+		},
+	})
+}
+
+// indent prefixes each line of the text with n spaces, so that the output of
+// include can be nested in indented formats such as YAML.
+func indent(n any, text string) (string, error) {
+	i, err := count(n)
+	if err != nil {
+		return "", err
+	} else if i < 0 {
+		return "", fmt.Errorf("expected non-negative integer; got %d", i)
+	}
+
+	pad := strings.Repeat(" ", i)
+
+	return pad + strings.ReplaceAll(text, "\n", "\n"+pad), nil
+}
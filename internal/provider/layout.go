@@ -0,0 +1,134 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"go.austindrenski.io/gotter/templates"
+)
+
+// layout is a template in an inheritance chain, where path is empty for
+// template text that was not read from a file.
+type layout struct {
+	name string
+	path string
+	text string
+}
+
+// String returns the path of the layout, or a description of the template
+// text, for use in errors.
+func (l layout) String() string {
+	if l.path == "" {
+		return "the template"
+	}
+
+	return strconv.Quote(l.path)
+}
+
+// extendsDirective matches `{{/* extends "base.tmpl" */}}` comments, with
+// optional trim markers, using the given delimiters.
+func extendsDirective(left string, right string) *regexp.Regexp {
+	return regexp.MustCompile(regexp.QuoteMeta(left) + `(?:- )?/\*\s*extends\s+"([^"]*)"\s*\*/(?: -)?` + regexp.QuoteMeta(right))
+}
+
+// layouts returns the inheritance chain of the entry template, starting with
+// the entry template itself and ending with the base layout that extends no
+// other. The entry template extends the layout named by extends, if any, or
+// else the layout named by its extends directive. Relative paths are resolved
+// against the directory of the extending file, or the working directory for
+// template text.
+func layouts(entry layout, extends *string, left string, right string) ([]layout, error) {
+	directive := extendsDirective(left, right)
+
+	chain := []layout{entry}
+	seen := map[string]bool{}
+
+	if entry.path != "" {
+		if abs, err := filepath.Abs(entry.path); err != nil {
+			return nil, err
+		} else {
+			seen[abs] = true
+		}
+	}
+
+	for {
+		child := chain[len(chain)-1]
+
+		var parent string
+
+		if matches := directive.FindAllStringSubmatch(child.text, -1); len(chain) == 1 && extends != nil {
+			parent = *extends
+		} else if len(matches) > 1 {
+			return nil, fmt.Errorf("%s extends more than one layout", child)
+		} else if len(matches) == 1 {
+			parent = matches[0][1]
+		} else {
+			return chain, nil
+		}
+
+		if parent == "" {
+			return nil, fmt.Errorf("%s extends an empty path", child)
+		} else if !filepath.IsAbs(parent) && child.path != "" {
+			parent = filepath.Join(filepath.Dir(child.path), parent)
+		}
+
+		abs, err := filepath.Abs(parent)
+		if err != nil {
+			return nil, err
+		} else if seen[abs] {
+			var paths []string
+			for _, l := range chain {
+				paths = append(paths, l.String())
+			}
+
+			return nil, fmt.Errorf("%s extends %q, which introduces a cycle: %s -> %q", child, parent, strings.Join(paths, " -> "), parent)
+		}
+
+		b, err := os.ReadFile(parent)
+		if err != nil {
+			return nil, fmt.Errorf("%s extends %q: %w", child, parent, err)
+		}
+
+		seen[abs] = true
+		chain = append(chain, layout{name: filepath.Base(parent), path: parent, text: string(b)})
+	}
+}
+
+// parseLayouts parses an inheritance chain from the base layout down to the
+// entry template, so that the templates defined by each layout replace those
+// of the layouts it extends and the most derived definition wins. The base
+// layout is the template that is executed, so text outside of the definitions
+// of the other layouts is ignored.
+func parseLayouts(ctx context.Context, chain []layout, prepare func(string) string, opts ...func(context.Context, *template.Template) *template.Template) (*template.Template, error) {
+	opts = append([]func(context.Context, *template.Template) *template.Template{templates.WithFuncs(functions)}, opts...)
+
+	var t *template.Template
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		l := chain[i]
+
+		p, err := templates.Parse(ctx, l.name, prepare(l.text), opts...)
+		if err != nil {
+			return nil, err
+		} else if t == nil {
+			t = p
+			continue
+		}
+
+		for _, d := range p.Templates() {
+			if d.Name() == l.name || d.Name() == t.Name() {
+				continue
+			} else if _, err := t.AddParseTree(d.Name(), d.Tree); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return t, nil
+}
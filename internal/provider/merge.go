@@ -0,0 +1,152 @@
+package provider
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// listMerges lists the supported list_merge modes.
+var listMerges = []string{"append", "key", "replace"}
+
+// merge deep merges the layers over the data in order, so that later layers
+// take precedence, in the style of Helm values files. Objects are merged key
+// by key, null values leave the value beneath them in place, and lists are
+// merged as configured by list_merge.
+func (o executeOptions) merge(data any) (any, error) {
+	for i, layer := range o.layers {
+		var err error
+		if data, err = o.mergeValue("", data, layer); err != nil {
+			return nil, fmt.Errorf("layer %d: %w", i, err)
+		}
+	}
+
+	return data, nil
+}
+
+// mergeValue merges src over dst, where path locates both within the data.
+// Values that are not yet known replace whatever is beneath them, and make
+// unknown any object or list that would otherwise be merged with them.
+func (o executeOptions) mergeValue(path string, dst any, src any) (any, error) {
+	_, unknownDst := dst.(unknown)
+
+	switch src := src.(type) {
+	case nil:
+		return dst, nil
+	case map[string]any:
+		if unknownDst {
+			return dst, nil
+		}
+
+		d, ok := dst.(map[string]any)
+		if !ok {
+			return strip(src), nil
+		}
+
+		m := maps.Clone(d)
+
+		for _, k := range slices.Sorted(maps.Keys(src)) {
+			if src[k] == nil {
+				continue
+			}
+
+			v, err := o.mergeValue(path+"."+k, d[k], src[k])
+			if err != nil {
+				return nil, err
+			}
+
+			m[k] = v
+		}
+
+		return m, nil
+	case []any:
+		if o.listMerge == "" || o.listMerge == "replace" {
+			return src, nil
+		} else if unknownDst {
+			return dst, nil
+		}
+
+		d, ok := dst.([]any)
+		if !ok {
+			return src, nil
+		} else if o.listMerge == "append" {
+			return slices.Concat(d, src), nil
+		}
+
+		return o.mergeKeyed(path, d, src)
+	default:
+		return src, nil
+	}
+}
+
+// mergeKeyed merges the elements of src over the elements of dst that have
+// the same list_merge_key, appending those that have no match. Elements must
+// be objects that hold the key.
+func (o executeOptions) mergeKeyed(path string, dst []any, src []any) (any, error) {
+	s := slices.Clone(dst)
+	keys := make([]any, len(s))
+
+	for i, v := range s {
+		if k, ok, err := o.elementKey(path, i, v); err != nil || !ok {
+			return unknown{}, err
+		} else {
+			keys[i] = k
+		}
+	}
+
+	for i, v := range src {
+		k, ok, err := o.elementKey(path, i, v)
+		if err != nil || !ok {
+			return unknown{}, err
+		}
+
+		j := slices.IndexFunc(keys, func(key any) bool {
+			return compare(key, k) == 0
+		})
+
+		if j < 0 {
+			s = append(s, v)
+			keys = append(keys, k)
+		} else if s[j], err = o.mergeValue(fmt.Sprintf("%s[%d]", path, j), s[j], v); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// elementKey returns the list_merge_key of a list element, reporting false if
+// the element or its key is not yet known.
+func (o executeOptions) elementKey(path string, i int, v any) (any, bool, error) {
+	if _, ok := v.(unknown); ok {
+		return nil, false, nil
+	}
+
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, false, fmt.Errorf("%s[%d]: list elements must be objects to be merged by key", path, i)
+	} else if m[o.listMergeKey] == nil {
+		return nil, false, fmt.Errorf("%s[%d]: list elements must have a %q key to be merged by key", path, i, o.listMergeKey)
+	} else if !known(m[o.listMergeKey]) {
+		return nil, false, nil
+	}
+
+	return m[o.listMergeKey], true, nil
+}
+
+// strip returns a copy of the object without its null values, at every depth
+// of nested objects, so that null values in a layer are ignored whether or not
+// there is an object beneath them to merge with.
+func strip(m map[string]any) map[string]any {
+	s := make(map[string]any, len(m))
+
+	for k, v := range m {
+		if object, ok := v.(map[string]any); ok {
+			s[k] = strip(object)
+		} else if v != nil {
+			s[k] = v
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,338 @@
+package provider
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// mustacheDepth limits the nesting of partials, so that a partial that
+// unconditionally includes itself fails instead of recursing forever.
+const mustacheDepth = 100
+
+// mustacheEscaper escapes interpolated values as required by the Mustache
+// specification.
+var mustacheEscaper = strings.NewReplacer(`&`, "&amp;", `"`, "&quot;", `<`, "&lt;", `>`, "&gt;")
+
+type mustachePartial struct {
+	indent string
+	name   string
+}
+
+type mustacheSection struct {
+	inverted bool
+	name     string
+	nodes    []any
+}
+
+type mustacheText string
+
+type mustacheVariable struct {
+	name string
+	raw  bool
+}
+
+// parseMustache parses a Mustache template using the given initial
+// delimiters. The result holds mustacheText, mustacheVariable,
+// mustacheSection and mustachePartial nodes.
+//
+// Tags that open or close sections, comments, partials and delimiter changes
+// are standalone when they are the only content on their line, in which case
+// the whole line is removed from the output.
+func parseMustache(text string, left string, right string) ([]any, error) {
+	root := &mustacheSection{}
+	stack := []*mustacheSection{root}
+
+	emit := func(node any) {
+		top := stack[len(stack)-1]
+		top.nodes = append(top.nodes, node)
+	}
+
+	for pos := 0; pos < len(text); {
+		i := strings.Index(text[pos:], left)
+		if i < 0 {
+			emit(mustacheText(text[pos:]))
+			break
+		}
+
+		start := pos + i
+		inner := start + len(left)
+
+		var sigil byte
+		if inner < len(text) && strings.IndexByte("#^/!>=&{", text[inner]) >= 0 {
+			sigil = text[inner]
+			inner++
+		}
+
+		closing := right
+		if sigil == '{' {
+			closing = "}" + right
+		} else if sigil == '=' {
+			closing = "=" + right
+		}
+
+		j := strings.Index(text[inner:], closing)
+		if j < 0 {
+			return nil, fmt.Errorf("line %d: unclosed tag", lineNumber(text, start))
+		}
+
+		content := strings.TrimSpace(text[inner : inner+j])
+		end := inner + j + len(closing)
+
+		if content == "" && sigil != '!' {
+			return nil, fmt.Errorf("line %d: empty tag", lineNumber(text, start))
+		}
+
+		// Remove the line of a standalone tag, keeping its indentation for
+		// partials.
+		var indent string
+		if sigil != 0 && strings.IndexByte("#^/!>=", sigil) >= 0 {
+			lineStart := strings.LastIndexByte(text[:start], '\n') + 1
+
+			lineEnd := strings.IndexByte(text[end:], '\n')
+			if lineEnd < 0 {
+				lineEnd = len(text)
+			} else {
+				lineEnd += end + 1
+			}
+
+			if whitespace(text[lineStart:start]) && whitespace(strings.TrimSuffix(strings.TrimSuffix(text[end:lineEnd], "\n"), "\r")) {
+				indent = text[lineStart:start]
+				start, end = lineStart, lineEnd
+			}
+		}
+
+		if start > pos {
+			emit(mustacheText(text[pos:start]))
+		}
+
+		pos = end
+
+		switch sigil {
+		case '!':
+		case '#', '^':
+			section := &mustacheSection{inverted: sigil == '^', name: content}
+			emit(section)
+			stack = append(stack, section)
+		case '/':
+			if top := stack[len(stack)-1]; len(stack) == 1 {
+				return nil, fmt.Errorf("line %d: unexpected closing tag %q", lineNumber(text, start), content)
+			} else if top.name != content {
+				return nil, fmt.Errorf("line %d: section %q is closed by %q", lineNumber(text, start), top.name, content)
+			}
+
+			stack = stack[:len(stack)-1]
+		case '>':
+			emit(mustachePartial{indent: indent, name: content})
+		case '=':
+			delims := strings.Fields(content)
+			if len(delims) != 2 {
+				return nil, fmt.Errorf("line %d: invalid delimiters %q", lineNumber(text, start), content)
+			}
+
+			left, right = delims[0], delims[1]
+		case '&', '{':
+			emit(mustacheVariable{name: content, raw: true})
+		default:
+			emit(mustacheVariable{name: content})
+		}
+	}
+
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("section %q is not closed", stack[len(stack)-1].name)
+	}
+
+	return root.nodes, nil
+}
+
+// whitespace reports whether the text only contains spaces and tabs.
+func whitespace(s string) bool {
+	return strings.Trim(s, " \t") == ""
+}
+
+// lineNumber returns the line number of the offset in the text.
+func lineNumber(text string, offset int) int {
+	return strings.Count(text[:offset], "\n") + 1
+}
+
+// mustacheRenderer renders parsed Mustache templates against the unwrapped
+// data. Lambdas are not supported, since Terraform values cannot hold
+// functions.
+type mustacheRenderer struct {
+	b        strings.Builder
+	depth    int
+	left     string
+	partials map[string]string
+	right    string
+	strict   bool
+}
+
+func (r *mustacheRenderer) render(nodes []any, stack []any) error {
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case mustacheText:
+			r.b.WriteString(string(node))
+		case mustacheVariable:
+			v, err := r.lookup(stack, node.name)
+			if err != nil {
+				return err
+			} else if !known(v) {
+				return errUnknown
+			}
+
+			s := mustacheString(v)
+			if !node.raw {
+				s = mustacheEscaper.Replace(s)
+			}
+
+			r.b.WriteString(s)
+		case *mustacheSection:
+			v, err := r.lookup(stack, node.name)
+			if err != nil {
+				return err
+			} else if _, ok := v.(unknown); ok {
+				return errUnknown
+			}
+
+			if node.inverted {
+				if !truthy(v) {
+					err = r.render(node.nodes, stack)
+				}
+			} else if items, ok := v.([]any); ok {
+				for _, item := range items {
+					if err = r.render(node.nodes, append(stack[:len(stack):len(stack)], item)); err != nil {
+						break
+					}
+				}
+			} else if truthy(v) {
+				err = r.render(node.nodes, append(stack[:len(stack):len(stack)], v))
+			}
+
+			if err != nil {
+				return err
+			}
+		case mustachePartial:
+			if err := r.partial(node, stack); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// partial renders the named partial, if any, indenting each of its lines by
+// the indentation of a standalone partial tag.
+func (r *mustacheRenderer) partial(node mustachePartial, stack []any) error {
+	text, ok := r.partials[node.name]
+	if !ok {
+		return nil
+	} else if r.depth == mustacheDepth {
+		return fmt.Errorf("partial %q exceeds the maximum depth of %d", node.name, mustacheDepth)
+	}
+
+	if node.indent != "" {
+		lines := strings.SplitAfter(text, "\n")
+
+		for i, line := range lines {
+			if line != "" {
+				lines[i] = node.indent + line
+			}
+		}
+
+		text = strings.Join(lines, "")
+	}
+
+	nodes, err := parseMustache(text, r.left, r.right)
+	if err != nil {
+		return fmt.Errorf("partial %q: %w", node.name, err)
+	}
+
+	r.depth++
+	defer func() { r.depth-- }()
+
+	return r.render(nodes, stack)
+}
+
+// lookup resolves a name, which may be dotted, against the context stack.
+// The first segment is resolved against each context from the innermost
+// outwards, and the remaining segments against the resulting value only.
+func (r *mustacheRenderer) lookup(stack []any, name string) (any, error) {
+	if name == "." {
+		return stack[len(stack)-1], nil
+	}
+
+	segments := strings.Split(name, ".")
+
+	var v any
+	var found bool
+
+	for i := len(stack) - 1; i >= 0 && !found; i-- {
+		if _, ok := stack[i].(unknown); ok {
+			return nil, errUnknown
+		} else if m, ok := stack[i].(map[string]any); ok {
+			v, found = m[segments[0]]
+		}
+	}
+
+	for _, segment := range segments[1:] {
+		if !found {
+			break
+		} else if _, ok := v.(unknown); ok {
+			return nil, errUnknown
+		} else if m, ok := v.(map[string]any); ok {
+			v, found = m[segment]
+		} else {
+			v, found = nil, false
+		}
+	}
+
+	if !found && r.strict {
+		return nil, fmt.Errorf("missing variable %q", name)
+	}
+
+	return v, nil
+}
+
+// truthy reports whether a section for the value is rendered, where null,
+// false, the empty string and empty lists are falsey.
+func truthy(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case []any:
+		return len(v) > 0
+	default:
+		return true
+	}
+}
+
+func mustacheString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case *big.Int:
+		return v.String()
+	case *big.Float:
+		return v.Text('f', -1)
+	default:
+		if s, err := marshal(v); err != nil {
+			return fmt.Sprint(v)
+		} else {
+			return s
+		}
+	}
+}
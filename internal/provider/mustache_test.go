@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMustacheSpec runs the fixtures in testdata/mustache, which follow the
+// layout of the Mustache specification, against the Mustache engine.
+func TestMustacheSpec(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "mustache", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var spec struct {
+			Tests []struct {
+				Data     any               `json:"data"`
+				Expected string            `json:"expected"`
+				Name     string            `json:"name"`
+				Partials map[string]string `json:"partials"`
+				Template string            `json:"template"`
+			} `json:"tests"`
+		}
+
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.UseNumber()
+
+		if err := d.Decode(&spec); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+
+		for _, test := range spec.Tests {
+			t.Run(filepath.Base(file)+"/"+test.Name, func(t *testing.T) {
+				nodes, err := parseMustache(test.Template, "{{", "}}")
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				r := mustacheRenderer{left: "{{", partials: test.Partials, right: "}}"}
+				if err := r.render(nodes, []any{specValue(t, test.Data)}); err != nil {
+					t.Fatal(err)
+				} else if s := r.b.String(); s != test.Expected {
+					t.Errorf("expected %q, got %q", test.Expected, s)
+				}
+			})
+		}
+	}
+}
+
+// specValue converts decoded JSON into the shape produced by unwrap.
+func specValue(t *testing.T, v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		f, _, err := big.ParseFloat(v.String(), 10, 512, big.ToNearestEven)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return number(f)
+	case map[string]any:
+		for k, e := range v {
+			v[k] = specValue(t, e)
+		}
+
+		return v
+	case []any:
+		for i, e := range v {
+			v[i] = specValue(t, e)
+		}
+
+		return v
+	default:
+		return v
+	}
+}
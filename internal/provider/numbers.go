@@ -0,0 +1,280 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+// precision is the number of mantissa bits used for exact arithmetic,
+// matching the precision Terraform uses when decoding numbers.
+const precision = 512
+
+var (
+	errDivisionByZero = errors.New("division by zero")
+	errNotInteger     = errors.New("expected integer operands")
+)
+
+// number narrows a Terraform number to the simplest Go type that represents
+// it exactly: int64 for integers that fit, float64 for values that survive a
+// round trip, and *big.Int or *big.Float otherwise.
+func number(f *big.Float) any {
+	if f.IsInt() {
+		if i, accuracy := f.Int64(); accuracy == big.Exact {
+			return i
+		}
+
+		i, _ := f.Int(nil)
+		return i
+	}
+
+	if f.IsInf() {
+		return f
+	}
+
+	v, _ := f.Float64()
+
+	if g, _, err := big.ParseFloat(strconv.FormatFloat(v, 'g', -1, 64), 10, f.Prec(), big.ToNearestEven); err == nil && g.Cmp(f) == 0 {
+		return v
+	}
+
+	return f
+}
+
+// numeric reports the value as a *big.Float if it is any Go numeric type.
+// Floating point values are widened from their shortest decimal
+// representation, so that 0.1 is widened to 0.1 rather than to the binary
+// fraction nearest to it.
+func numeric(v any) (*big.Float, bool) {
+	switch v := v.(type) {
+	case *big.Float:
+		return v, v != nil
+	case *big.Int:
+		return new(big.Float).SetPrec(precision).SetInt(v), v != nil
+	}
+
+	r := reflect.ValueOf(v)
+
+	switch r.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return new(big.Float).SetPrec(precision).SetInt64(r.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return new(big.Float).SetPrec(precision).SetUint64(r.Uint()), true
+	case reflect.Float32:
+		return new(big.Float).SetPrec(precision).SetString(strconv.FormatFloat(r.Float(), 'g', -1, 32))
+	case reflect.Float64:
+		return new(big.Float).SetPrec(precision).SetString(strconv.FormatFloat(r.Float(), 'g', -1, 64))
+	default:
+		return nil, false
+	}
+}
+
+// integer reports whether the value is a Go integer type or a *big.Int.
+func integer(v any) bool {
+	switch v.(type) {
+	case *big.Int:
+		return true
+	case *big.Float:
+		return false
+	}
+
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	default:
+		return false
+	}
+}
+
+// arbitrary reports whether either value requires arbitrary precision.
+func arbitrary(a any, b any) bool {
+	switch a.(type) {
+	case *big.Float, *big.Int:
+		return true
+	}
+
+	switch b.(type) {
+	case *big.Float, *big.Int:
+		return true
+	}
+
+	return false
+}
+
+func arithmetic(a any, b any, op func(z, x, y *big.Float) *big.Float) (any, error) {
+	x, ok := numeric(a)
+	if !ok {
+		return nil, fmt.Errorf("expected number; got %T", a)
+	}
+
+	y, ok := numeric(b)
+	if !ok {
+		return nil, fmt.Errorf("expected number; got %T", b)
+	}
+
+	z := op(new(big.Float).SetPrec(precision), x, y)
+
+	if integer(a) && integer(b) || arbitrary(a, b) {
+		return number(z), nil
+	} else {
+		v, _ := z.Float64()
+		return v, nil
+	}
+}
+
+func add(a any, b any) (any, error) {
+	return arithmetic(a, b, (*big.Float).Add)
+}
+
+func sub(a any, b any) (any, error) {
+	return arithmetic(a, b, (*big.Float).Sub)
+}
+
+func mul(a any, b any) (any, error) {
+	return arithmetic(a, b, (*big.Float).Mul)
+}
+
+func div(a any, b any) (any, error) {
+	if y, ok := numeric(b); ok && y.Sign() == 0 {
+		return nil, errDivisionByZero
+	}
+
+	v, err := arithmetic(a, b, (*big.Float).Quo)
+	if err != nil {
+		return nil, err
+	}
+
+	if f, ok := v.(*big.Float); ok && !arbitrary(a, b) {
+		v, _ := f.Float64()
+		return v, nil
+	}
+
+	return v, nil
+}
+
+func mod(a any, b any) (any, error) {
+	if !integer(a) || !integer(b) {
+		return nil, errNotInteger
+	}
+
+	x, _ := numeric(a)
+	y, _ := numeric(b)
+
+	if y.Sign() == 0 {
+		return nil, errDivisionByZero
+	}
+
+	i, _ := x.Int(nil)
+	j, _ := y.Int(nil)
+
+	return number(new(big.Float).SetPrec(precision).SetInt(i.Rem(i, j))), nil
+}
+
+// count narrows a number to an int, for the functions that take a count or a
+// width, since numbers in the unwrapped data are int64 rather than int.
+func count(v any) (int, error) {
+	f, ok := numeric(v)
+	if !ok {
+		return 0, fmt.Errorf("expected integer; got %T", v)
+	} else if !f.IsInt() {
+		return 0, fmt.Errorf("expected integer; got %s", f.Text('g', -1))
+	}
+
+	if i, accuracy := f.Int64(); accuracy != big.Exact || int64(int(i)) != i {
+		return 0, fmt.Errorf("integer %s out of range", f.Text('g', -1))
+	} else {
+		return int(i), nil
+	}
+}
+
+// compareNumbers compares two values numerically, reporting false if either
+// value is not a number.
+func compareNumbers(a any, b any) (int, bool) {
+	x, ok := numeric(a)
+	if !ok {
+		return 0, false
+	}
+
+	y, ok := numeric(b)
+	if !ok {
+		return 0, false
+	}
+
+	return x.Cmp(y), true
+}
+
+// eq replaces the text/template builtin so that numbers of different Go types
+// compare by value.
+func eq(a any, b ...any) (bool, error) {
+	if len(b) == 0 {
+		return false, errors.New("missing argument for comparison")
+	}
+
+	for _, b := range b {
+		if c, ok := compareNumbers(a, b); ok {
+			if c == 0 {
+				return true, nil
+			}
+
+			continue
+		}
+
+		switch {
+		case a == nil || b == nil:
+			if a == nil && b == nil {
+				return true, nil
+			}
+		case reflect.TypeOf(a) != reflect.TypeOf(b):
+			return false, fmt.Errorf("incompatible types for comparison: %T and %T", a, b)
+		case !reflect.TypeOf(a).Comparable():
+			return false, fmt.Errorf("non-comparable type %T", a)
+		case a == b:
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func ne(a any, b any) (bool, error) {
+	v, err := eq(a, b)
+	return !v, err
+}
+
+// lt replaces the text/template builtin so that numbers of different Go types
+// compare by value.
+func lt(a any, b any) (bool, error) {
+	if c, ok := compareNumbers(a, b); ok {
+		return c < 0, nil
+	}
+
+	if x, ok := a.(string); ok {
+		if y, ok := b.(string); ok {
+			return x < y, nil
+		}
+	}
+
+	return false, fmt.Errorf("incompatible types for comparison: %T and %T", a, b)
+}
+
+func le(a any, b any) (bool, error) {
+	if v, err := lt(a, b); err != nil || v {
+		return v, err
+	}
+
+	return eq(a, b)
+}
+
+func gt(a any, b any) (bool, error) {
+	v, err := le(a, b)
+	return !v && err == nil, err
+}
+
+func ge(a any, b any) (bool, error) {
+	v, err := lt(a, b)
+	return !v && err == nil, err
+}
@@ -0,0 +1,124 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// emitters holds placeholders for the functions that an object binds to a
+// template, so that templates using them can be parsed before they are bound.
+var emitters = template.FuncMap{
+	"append": func(path string, _ any) (string, error) {
+		return "", fmt.Errorf("append %q is only available in execute_object", path)
+	},
+	"emit": func(path string, _ any) (string, error) {
+		return "", fmt.Errorf("emit %q is only available in execute_object", path)
+	},
+}
+
+// object collects the values that a template emits, keyed by dot-separated
+// paths such as "spec.replicas".
+type object struct {
+	root map[string]any
+}
+
+// funcs returns the emit and append functions bound to the object, which
+// print nothing so that only the collected values make up the result.
+func (o *object) funcs() template.FuncMap {
+	return template.FuncMap{
+		"append": func(path string, v any) (string, error) {
+			return "", o.append(path, v)
+		},
+		"emit": func(path string, v any) (string, error) {
+			return "", o.emit(path, v)
+		},
+	}
+}
+
+// emit sets the value at the path, replacing any value already there and
+// creating the objects that lead to it.
+func (o *object) emit(path string, v any) error {
+	m, key, err := o.parent("emit", path)
+	if err != nil {
+		return err
+	}
+
+	m[key] = clone(v)
+
+	return nil
+}
+
+// append adds the value to the end of the list at the path, creating the list
+// and the objects that lead to it.
+func (o *object) append(path string, v any) error {
+	m, key, err := o.parent("append", path)
+	if err != nil {
+		return err
+	}
+
+	if m[key] == nil {
+		m[key] = []any{clone(v)}
+	} else if s, ok := m[key].([]any); ok {
+		m[key] = append(s, clone(v))
+	} else {
+		return fmt.Errorf("append %q: %q is not a list", path, path)
+	}
+
+	return nil
+}
+
+// parent returns the object holding the last segment of the path, along with
+// that segment.
+func (o *object) parent(call string, path string) (map[string]any, string, error) {
+	segments := strings.Split(path, ".")
+
+	for _, segment := range segments {
+		if segment == "" {
+			return nil, "", fmt.Errorf("%s %q: path must be a dot-separated list of keys", call, path)
+		}
+	}
+
+	if o.root == nil {
+		o.root = map[string]any{}
+	}
+
+	m := o.root
+
+	for i, segment := range segments[:len(segments)-1] {
+		if m[segment] == nil {
+			m[segment] = map[string]any{}
+		}
+
+		if next, ok := m[segment].(map[string]any); ok {
+			m = next
+		} else {
+			return nil, "", fmt.Errorf("%s %q: %q is not an object", call, path, strings.Join(segments[:i+1], "."))
+		}
+	}
+
+	return m, segments[len(segments)-1], nil
+}
+
+// clone copies the objects and lists within the value, so that emitting into
+// a value taken from the data does not modify the data.
+func clone(v any) any {
+	switch v := v.(type) {
+	case []any:
+		s := make([]any, len(v))
+		for i, v := range v {
+			s[i] = clone(v)
+		}
+
+		return s
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, v := range v {
+			m[k] = clone(v)
+		}
+
+		return m
+	default:
+		return v
+	}
+}
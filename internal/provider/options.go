@@ -0,0 +1,291 @@
+package provider
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+)
+
+// executeOptions configures how the template passed to execute or
+// execute_file is parsed and which of its templates is executed. It holds the
+// partials of each options object separately, so that errors can be
+// attributed to the object that declared them.
+type executeOptions struct {
+	engine       string
+	escape       string
+	extends      *string
+	layers       []any
+	leftDelim    string
+	listMerge    string
+	listMergeKey string
+	lstripBlocks bool
+	missingKey   string
+	partials     []map[string]string
+	plugins      map[string]plugin
+	rightDelim   string
+	sections     bool
+	template     *string
+	trimBlocks   bool
+}
+
+// engines lists the supported template engines.
+var engines = []string{"go", "mustache"}
+
+// missingKeys lists the supported missing_key modes.
+var missingKeys = []string{"default", "error", "zero"}
+
+// set merges an options object into o, with values from later objects
+// replacing those from earlier ones. Partials are accumulated instead, so
+// that collisions between objects can be reported, as are layers, so that
+// every layer is merged over the data.
+func (o *executeOptions) set(v attr.Value) error {
+	m, ok := unwrap(v).(map[string]any)
+	if !ok {
+		return errors.New("options must be an object")
+	}
+
+	var partials map[string]string
+
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		if m[key] == nil {
+			continue
+		}
+
+		var err error
+
+		switch key {
+		case "engine":
+			if o.engine, err = optionString(key, m[key]); err == nil && !slices.Contains(engines, o.engine) {
+				err = fmt.Errorf("option %q must be one of %s, got %q", key, strings.Join(engines, ", "), o.engine)
+			}
+		case "escape":
+			if o.escape, err = optionString(key, m[key]); err == nil && !slices.Contains(escapeModes, o.escape) {
+				err = fmt.Errorf("option %q must be one of %s, got %q", key, strings.Join(escapeModes, ", "), o.escape)
+			}
+		case "extends":
+			var path string
+			if path, err = optionString(key, m[key]); err == nil {
+				o.extends = &path
+			}
+		case "layers":
+			if _, ok := m[key].(unknown); ok {
+				o.layers = append(o.layers, m[key])
+			} else if layers, ok := m[key].([]any); !ok {
+				err = fmt.Errorf("option %q must be a list", key)
+			} else {
+				o.layers = append(o.layers, layers...)
+			}
+		case "left_delim":
+			o.leftDelim, err = optionString(key, m[key])
+		case "list_merge":
+			if o.listMerge, err = optionString(key, m[key]); err == nil && !slices.Contains(listMerges, o.listMerge) {
+				err = fmt.Errorf("option %q must be one of %s, got %q", key, strings.Join(listMerges, ", "), o.listMerge)
+			}
+		case "list_merge_key":
+			o.listMergeKey, err = optionString(key, m[key])
+		case "lstrip_blocks":
+			o.lstripBlocks, err = optionBool(key, m[key])
+		case "missing_key":
+			if o.missingKey, err = optionString(key, m[key]); err == nil && !slices.Contains(missingKeys, o.missingKey) {
+				err = fmt.Errorf("option %q must be one of %s, got %q", key, strings.Join(missingKeys, ", "), o.missingKey)
+			}
+		case "partials":
+			partials, err = optionStrings(key, m[key])
+		case "plugins":
+			var plugins map[string]plugin
+			if plugins, err = optionPlugins(key, m[key]); err == nil {
+				if o.plugins == nil {
+					o.plugins = map[string]plugin{}
+				}
+
+				maps.Copy(o.plugins, plugins)
+			}
+		case "right_delim":
+			o.rightDelim, err = optionString(key, m[key])
+		case "template":
+			var name string
+			if name, err = optionString(key, m[key]); err == nil {
+				o.template = &name
+			}
+		case "trim_blocks":
+			o.trimBlocks, err = optionBool(key, m[key])
+		default:
+			err = fmt.Errorf("unsupported option %q; expected one of engine, escape, extends, layers, left_delim, list_merge, list_merge_key, lstrip_blocks, missing_key, partials, plugins, right_delim, template, trim_blocks", key)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	o.partials = append(o.partials, partials)
+
+	return nil
+}
+
+// settings returns the unwrapped options object without its layers, which
+// are data rather than configuration, and so may hold values that are not
+// yet known.
+func settings(v attr.Value) any {
+	m, ok := unwrap(v).(map[string]any)
+	if !ok {
+		return unwrap(v)
+	}
+
+	m = maps.Clone(m)
+	delete(m, "layers")
+
+	return m
+}
+
+// parse returns the parse options for the template.
+func (o executeOptions) parse() []func(context.Context, *template.Template) *template.Template {
+	var opts []func(context.Context, *template.Template) *template.Template
+
+	if o.leftDelim != "" || o.rightDelim != "" {
+		opts = append(opts, withDelims(o.leftDelim, o.rightDelim))
+	}
+
+	if o.missingKey != "" {
+		opts = append(opts, withMissingKey(o.missingKey))
+	}
+
+	if o.plugins != nil {
+		opts = append(opts, withPlugins(o.plugins))
+	}
+
+	return opts
+}
+
+// prepare returns the template text ready to parse, with its section actions
+// rewritten when sections is set by execute_sections, and its block lines
+// trimmed as configured by trim_blocks and lstrip_blocks.
+func (o executeOptions) prepare(text string) string {
+	left, right := o.delims()
+
+	if o.sections {
+		text = rewriteSections(text, left, right)
+	}
+
+	if !o.trimBlocks && !o.lstripBlocks {
+		return text
+	}
+
+	return trimBlocks(text, left, right, o.trimBlocks, o.lstripBlocks)
+}
+
+// delims returns the action delimiters, replacing empty delimiters with the
+// defaults.
+func (o executeOptions) delims() (string, string) {
+	return cmp.Or(o.leftDelim, "{{"), cmp.Or(o.rightDelim, "}}")
+}
+
+func optionBool(key string, v any) (bool, error) {
+	if b, ok := v.(bool); !ok {
+		return false, fmt.Errorf("option %q must be a bool", key)
+	} else {
+		return b, nil
+	}
+}
+
+func optionString(key string, v any) (string, error) {
+	if s, ok := v.(string); !ok {
+		return "", fmt.Errorf("option %q must be a string", key)
+	} else {
+		return s, nil
+	}
+}
+
+func optionStrings(key string, v any) (map[string]string, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("option %q must be a map of strings", key)
+	}
+
+	s := make(map[string]string, len(m))
+
+	for k, v := range m {
+		if v, ok := v.(string); !ok {
+			return nil, fmt.Errorf("option %q must be a map of strings", key)
+		} else {
+			s[k] = v
+		}
+	}
+
+	return s, nil
+}
+
+// withDelims sets the action delimiters, where an empty delimiter selects the
+// default, in the style of templates.WithFuncs.
+func withDelims(left string, right string) func(context.Context, *template.Template) *template.Template {
+	return func(_ context.Context, t *template.Template) *template.Template {
+		return t.Delims(left, right)
+	}
+}
+
+// withMissingKey sets the behavior when the data has no entry for a key, in
+// the style of templates.WithFuncs.
+func withMissingKey(mode string) func(context.Context, *template.Template) *template.Template {
+	return func(_ context.Context, t *template.Template) *template.Template {
+		return t.Option("missingkey=" + mode)
+	}
+}
+
+// blank rewrites the parse trees of t and its associated templates so that
+// actions print nothing for missing and null values, rather than
+// "<no value>", since the zero value of an entry in the unwrapped data is a
+// nil interface.
+func blank(t *template.Template) {
+	t.Funcs(template.FuncMap{
+		"_blank": func(v any) any {
+			if v == nil {
+				return ""
+			} else {
+				return v
+			}
+		},
+	})
+
+	for _, t := range t.Templates() {
+		if t.Tree != nil && t.Tree.Root != nil {
+			blankNode(t.Tree.Root)
+		}
+	}
+}
+
+func blankNode(node parse.Node) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		for _, n := range node.Nodes {
+			blankNode(n)
+		}
+	case *parse.ActionNode:
+		if len(node.Pipe.Decl) == 0 {
+			node.Pipe.Cmds = append(node.Pipe.Cmds, command(node.Pos, identifier(node.Pos, "_blank")))
+		}
+	case *parse.IfNode:
+		blankBranch(&node.BranchNode)
+	case *parse.RangeNode:
+		blankBranch(&node.BranchNode)
+	case *parse.WithNode:
+		blankBranch(&node.BranchNode)
+	}
+}
+
+func blankBranch(node *parse.BranchNode) {
+	if node.List != nil {
+		blankNode(node.List)
+	}
+
+	if node.ElseList != nil {
+		blankNode(node.ElseList)
+	}
+}
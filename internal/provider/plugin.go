@@ -0,0 +1,218 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// pluginAllowlist names the environment variable that lists the programs
+// that plugins may run, separated by the OS path list separator. Entries are
+// absolute paths or filepath.Match patterns, e.g. "/opt/tools/*". Plugins are
+// refused when it is unset, since templates are often shared between teams.
+const pluginAllowlist = "GOTTER_PLUGIN_ALLOWLIST"
+
+// pluginTimeout is the timeout of a plugin that does not configure one.
+const pluginTimeout = 10 * time.Second
+
+// pluginStderrLimit is the number of characters of the last line that a
+// failing plugin writes to stderr that is reported in the error.
+const pluginStderrLimit = 200
+
+// plugin is a template function backed by a local executable. Each call runs
+// program with a JSON request of the form {"args": [...], "function": "name"}
+// on stdin and reads a JSON response of the form {"result": ...} or
+// {"error": "message"} from stdout, in the style of the external data source.
+type plugin struct {
+	program []string
+	timeout time.Duration
+}
+
+// optionPlugins parses the plugins option, a map from function names to
+// objects holding the program to run and an optional timeout.
+func optionPlugins(key string, v any) (map[string]plugin, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("option %q must be a map of objects", key)
+	}
+
+	plugins := make(map[string]plugin, len(m))
+
+	for _, name := range slices.Sorted(maps.Keys(m)) {
+		v := m[name]
+
+		if !funcName.MatchString(name) {
+			return nil, fmt.Errorf("option %q has invalid function name %q", key, name)
+		}
+
+		p, err := parsePlugin(v)
+		if err != nil {
+			return nil, fmt.Errorf("option %q has invalid plugin %q: %w", key, name, err)
+		}
+
+		plugins[name] = p
+	}
+
+	return plugins, nil
+}
+
+func parsePlugin(v any) (plugin, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return plugin{}, errors.New("plugin must be an object")
+	}
+
+	p := plugin{timeout: pluginTimeout}
+
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		v := m[key]
+
+		switch key {
+		case "program":
+			args, ok := v.([]any)
+			if !ok || len(args) == 0 {
+				return plugin{}, errors.New(`"program" must be a non-empty list of strings`)
+			}
+
+			for _, arg := range args {
+				if s, ok := arg.(string); !ok {
+					return plugin{}, errors.New(`"program" must be a non-empty list of strings`)
+				} else {
+					p.program = append(p.program, s)
+				}
+			}
+		case "timeout":
+			if s, ok := v.(string); !ok {
+				return plugin{}, errors.New(`"timeout" must be a duration such as "5s"`)
+			} else if d, err := time.ParseDuration(s); err != nil || d <= 0 {
+				return plugin{}, errors.New(`"timeout" must be a duration such as "5s"`)
+			} else {
+				p.timeout = d
+			}
+		default:
+			return plugin{}, fmt.Errorf("unsupported key %q; expected one of program, timeout", key)
+		}
+	}
+
+	if p.program == nil {
+		return plugin{}, errors.New(`"program" is required`)
+	}
+
+	path, err := exec.LookPath(p.program[0])
+	if err != nil {
+		return plugin{}, err
+	} else if path, err = filepath.Abs(path); err != nil {
+		return plugin{}, err
+	} else if !allowed(path) {
+		return plugin{}, fmt.Errorf("program %q is not allowed; add it to %s", path, pluginAllowlist)
+	}
+
+	p.program[0] = path
+
+	return p, nil
+}
+
+// allowed reports whether the allowlist permits running the program.
+func allowed(path string) bool {
+	for _, entry := range filepath.SplitList(os.Getenv(pluginAllowlist)) {
+		if entry == "" {
+			continue
+		} else if ok, err := filepath.Match(filepath.Clean(entry), path); err == nil && ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withPlugins registers a function for each plugin, in the style of
+// templates.WithFuncs.
+func withPlugins(plugins map[string]plugin) func(context.Context, *template.Template) *template.Template {
+	return func(ctx context.Context, t *template.Template) *template.Template {
+		m := template.FuncMap{}
+
+		for name, p := range plugins {
+			m[name] = func(args ...any) (any, error) {
+				return p.call(ctx, name, args)
+			}
+		}
+
+		return t.Funcs(m)
+	}
+}
+
+// call runs the plugin for a single function call. Errors include the last
+// line that a failing program writes to stderr, truncated, so programs should
+// keep timestamps and process IDs out of it for errors to read the same way on
+// every plan.
+func (p plugin) call(ctx context.Context, name string, args []any) (any, error) {
+	if args == nil {
+		args = []any{}
+	}
+
+	request, err := marshal(map[string]any{"args": args, "function": name})
+	if err != nil {
+		return nil, fmt.Errorf("plugin %q: %w", name, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	var exit *exec.ExitError
+
+	cmd := exec.CommandContext(ctx, p.program[0], p.program[1:]...)
+	cmd.Stdin = strings.NewReader(request)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// Programs that leave children holding stdout open are not waited on for
+	// longer than it takes to notice the timeout.
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("plugin %q timed out after %s", name, p.timeout)
+	} else if errors.As(err, &exit) {
+		return nil, fmt.Errorf("plugin %q exited with code %d: %s", name, exit.ExitCode(), lastLine(stderr.String(), pluginStderrLimit))
+	} else if err != nil {
+		return nil, fmt.Errorf("plugin %q: %w", name, err)
+	}
+
+	var response struct {
+		Error  *string         `json:"error"`
+		Result json.RawMessage `json:"result"`
+	}
+
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("plugin %q returned an invalid response: %w", name, err)
+	} else if response.Error != nil {
+		return nil, fmt.Errorf("plugin %q: %s", name, *response.Error)
+	} else if response.Result == nil {
+		return nil, fmt.Errorf("plugin %q returned an invalid response: missing \"result\"", name)
+	}
+
+	return decodeJSON(response.Result)
+}
+
+// lastLine returns the last non-blank line of the text, truncated to at most n
+// characters.
+func lastLine(text string, n int) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	line := []rune(strings.TrimSpace(lines[len(lines)-1]))
+
+	if len(line) > n {
+		return string(line[:n]) + "..."
+	}
+
+	return string(line)
+}
@@ -4,13 +4,17 @@ import (
 	"context"
 
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
 	"github.com/hashicorp/terraform-plugin-framework/function"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 )
 
-var _ provider.ProviderWithFunctions = (*gotterProvider)(nil)
+var (
+	_ provider.ProviderWithEphemeralResources = (*gotterProvider)(nil)
+	_ provider.ProviderWithFunctions          = (*gotterProvider)(nil)
+)
 
 type gotterProvider struct {
 	name    string
@@ -30,7 +34,19 @@ func (p gotterProvider) Configure(_ context.Context, _ provider.ConfigureRequest
 }
 
 func (p gotterProvider) DataSources(_ context.Context) []func() datasource.DataSource {
-	return nil
+	return []func() datasource.DataSource{
+		func() datasource.DataSource {
+			return templateDataSource{}
+		},
+	}
+}
+
+func (p gotterProvider) EphemeralResources(_ context.Context) []func() ephemeral.EphemeralResource {
+	return []func() ephemeral.EphemeralResource{
+		func() ephemeral.EphemeralResource {
+			return templateEphemeralResource{}
+		},
+	}
 }
 
 func (p gotterProvider) Functions(_ context.Context) []func() function.Function {
@@ -47,6 +63,97 @@ func (p gotterProvider) Functions(_ context.Context) []func() function.Function
 				name: "execute_file",
 			}
 		},
+		func() function.Function {
+			return executeEach{
+				name: "execute_each",
+			}
+		},
+		func() function.Function {
+			return executeEnvsubst{
+				file: false,
+				name: "execute_envsubst",
+			}
+		},
+		func() function.Function {
+			return executeEnvsubst{
+				file: true,
+				name: "execute_envsubst_file",
+			}
+		},
+		func() function.Function {
+			return executeGlob{
+				name: "execute_glob",
+			}
+		},
+		func() function.Function {
+			return executeHTML{
+				file: false,
+				name: "execute_html",
+			}
+		},
+		func() function.Function {
+			return executeHTML{
+				file: true,
+				name: "execute_html_file",
+			}
+		},
+		func() function.Function {
+			return executeDecode{
+				file:   false,
+				format: "json",
+				name:   "execute_json",
+			}
+		},
+		func() function.Function {
+			return executeDecode{
+				file:   true,
+				format: "json",
+				name:   "execute_json_file",
+			}
+		},
+		func() function.Function {
+			return executeMap{
+				name: "execute_map",
+			}
+		},
+		func() function.Function {
+			return executeObject{
+				file: false,
+				name: "execute_object",
+			}
+		},
+		func() function.Function {
+			return executeObject{
+				file: true,
+				name: "execute_object_file",
+			}
+		},
+		func() function.Function {
+			return executeSections{
+				file: false,
+				name: "execute_sections",
+			}
+		},
+		func() function.Function {
+			return executeSections{
+				file: true,
+				name: "execute_sections_file",
+			}
+		},
+		func() function.Function {
+			return executeDecode{
+				file:   false,
+				format: "yaml",
+				name:   "execute_yaml",
+			}
+		},
+		func() function.Function {
+			return executeDecode{
+				file:   true,
+				format: "yaml",
+				name:   "execute_yaml_file",
+			}
+		},
 	}
 }
 
@@ -56,11 +163,20 @@ func (p gotterProvider) Metadata(_ context.Context, _ provider.MetadataRequest,
 }
 
 func (p gotterProvider) Resources(_ context.Context) []func() resource.Resource {
-	return nil
+	return []func() resource.Resource{
+		func() resource.Resource {
+			return directoryResource{}
+		},
+		func() resource.Resource {
+			return fileResource{}
+		},
+	}
 }
 
 func (p gotterProvider) Schema(_ context.Context, _ provider.SchemaRequest, resp *provider.SchemaResponse) {
 	resp.Schema = schema.Schema{
-		Description: "A provider for Go text/template processing.",
+		Description: "A provider for Go text/template processing. " +
+			"Template functions backed by local executables, configured with the `plugins` option, may only run programs listed in the `" + pluginAllowlist + "` environment variable, " +
+			"separated by the OS path list separator, where each entry is an absolute path or a pattern such as `/opt/tools/*`. Plugins are refused when it is unset.",
 	}
 }
@@ -2,13 +2,19 @@ package provider
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
 	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
 	"github.com/hashicorp/terraform-plugin-testing/tfversion"
 )
 
@@ -63,6 +69,66 @@ func TestGotterProvider(t *testing.T) {
 			data:  `null`,
 			text:  `{{ print . }}`,
 		},
+		"conditional_with_false": {
+			check: knownvalue.StringExact("off"),
+			data:  `{ enabled = false }`,
+			text:  `{{ if .enabled }}on{{ else }}off{{ end }}`,
+		},
+		"conditional_with_null": {
+			check: knownvalue.StringExact("off"),
+			data:  `{ enabled = null }`,
+			text:  `{{ if .enabled }}on{{ else }}off{{ end }}`,
+		},
+		"nested_with_data": {
+			check: knownvalue.StringExact("value"),
+			data:  `{ outer = { inner = "value" } }`,
+			text:  `{{ .outer.inner }}`,
+		},
+		"number_with_arithmetic": {
+			check: knownvalue.StringExact("444 3.5 0.2"),
+			data:  `{ port = 443, ratio = 0.1 }`,
+			text:  `{{ add .port 1 }} {{ div 7 2 }} {{ mul .ratio 2 }}`,
+		},
+		"number_with_fraction_arithmetic": {
+			check: knownvalue.StringExact("0.3 0.3"),
+			data:  `{ ratio = 0.1 }`,
+			text:  `{{ add 0.1 0.2 }} {{ mul .ratio 3 }}`,
+		},
+		"number_with_comparison": {
+			check: knownvalue.StringExact("true true false"),
+			data:  `{ port = 443, ratio = 0.5 }`,
+			text:  `{{ eq .port 443 }} {{ lt .ratio 1 }} {{ gt .ratio .port }}`,
+		},
+		"number_with_fraction": {
+			check: knownvalue.StringExact("0.1"),
+			data:  `{ ratio = 0.1 }`,
+			text:  `{{ .ratio }}`,
+		},
+		"number_with_large_integer": {
+			check: knownvalue.StringExact("123456789012345678901234567890"),
+			data:  `{ size = 123456789012345678901234567890 }`,
+			text:  `{{ .size }}`,
+		},
+		"number_with_printf": {
+			check: knownvalue.StringExact("port=443"),
+			data:  `{ port = 443 }`,
+			text:  `{{ printf "port=%d" .port }}`,
+		},
+		"range_with_count": {
+			check: knownvalue.StringExact("012"),
+			data:  `{ replicas = 3 }`,
+			text:  `{{ range .replicas }}{{ . }}{{ end }}`,
+		},
+		"range_with_list": {
+			check: knownvalue.StringExact("a,b,"),
+			data:  `{ items = ["a", "b"] }`,
+			text:  `{{ range .items }}{{ . }},{{ end }}`,
+		},
+		"range_with_set": {
+			check: knownvalue.StringExact("a,b,c,"),
+			data:  `{ items = toset(["c", "a", "b"]) }`,
+			text:  `{{ range .items }}{{ . }},{{ end }}`,
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			resource.UnitTest(t, resource.TestCase{
@@ -84,3 +150,1467 @@ func TestGotterProvider(t *testing.T) {
 		})
 	}
 }
+
+func TestGotterProviderUnknown(t *testing.T) {
+	for name, test := range map[string]struct {
+		check plancheck.PlanCheck
+		error *regexp.Regexp
+		text  string
+	}{
+		"known_declaration": {
+			check: plancheck.ExpectKnownOutputValue("test", knownvalue.StringExact("svc")),
+			text:  `{{ $id := .id }}{{ .name }}`,
+		},
+		"known_error": {
+			error: regexp.MustCompile(`executing "" at <\.name\.first>: can't evaluate field first in type string`),
+			text:  `{{ .name.first }}`,
+		},
+		"known_path": {
+			check: plancheck.ExpectKnownOutputValue("test", knownvalue.StringExact("svc")),
+			text:  `{{ .name }}`,
+		},
+		"known_sibling": {
+			check: plancheck.ExpectKnownOutputValue("test", knownvalue.StringExact("a")),
+			text:  `{{ index .nested.list 0 }}`,
+		},
+		"unknown_path": {
+			check: plancheck.ExpectUnknownOutputValue("test"),
+			text:  `{{ .id }}`,
+		},
+		"unknown_nested": {
+			check: plancheck.ExpectUnknownOutputValue("test"),
+			text:  `{{ json .nested }}`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resource.UnitTest(t, resource.TestCase{
+				ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+					"gotter": providerserver.NewProtocol6WithError(New("dev")()),
+				},
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`
+resource "terraform_data" "test" {}
+
+output "test" {
+  value = provider::gotter::execute(%q, {
+    id     = terraform_data.test.id
+    name   = "svc"
+    nested = { list = ["a", terraform_data.test.id] }
+  })
+}`, test.text),
+						ConfigPlanChecks: resource.ConfigPlanChecks{
+							PreApply: []plancheck.PlanCheck{
+								test.check,
+							},
+						},
+						ExpectError: test.error,
+					},
+				},
+				TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+					tfversion.SkipBelow(tfversion.Version1_8_0),
+				},
+			})
+		})
+	}
+}
+
+func TestGotterProviderTemplateDataSource(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "page.tmpl")
+
+	for name, text := range map[string]string{
+		"base.tmpl": `<main>{{ block "content" . }}{{ end }}</main>`,
+		"page.tmpl": `{{/* extends "base.tmpl" */}}{{ define "content" }}hello {{ .name }}{{ end }}`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+			"gotter": providerserver.NewProtocol6WithError(New("dev")()),
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: `
+data "gotter_template" "test" {
+  text = "hello {{ .name }}"
+  data = { name = "world" }
+}`,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("data.gotter_template.test", tfjsonpath.New("rendered"), knownvalue.StringExact("hello world")),
+					statecheck.ExpectKnownValue("data.gotter_template.test", tfjsonpath.New("sha256"), knownvalue.StringExact("b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9")),
+					statecheck.ExpectKnownValue("data.gotter_template.test", tfjsonpath.New("base64sha256"), knownvalue.StringExact("uU0nuZNNPgilLlLX2n2r+sSE7+N6U4DukIj3rOLvzek=")),
+				},
+			},
+			{
+				Config: `
+data "gotter_template" "test" {
+  text    = "<% .greeting %> <% .name %>"
+  data    = { greeting = "hello", name = "world" }
+  options = { left_delim = "<%", right_delim = "%>", layers = [{ name = "layers" }] }
+}`,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("data.gotter_template.test", tfjsonpath.New("rendered"), knownvalue.StringExact("hello layers")),
+				},
+			},
+			{
+				Config: fmt.Sprintf(`
+data "gotter_template" "test" {
+  file = %q
+  data = { name = "world" }
+}`, page),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("data.gotter_template.test", tfjsonpath.New("rendered"), knownvalue.StringExact("<main>hello world</main>")),
+				},
+			},
+			{
+				Config: `
+data "gotter_template" "test" {
+  text    = "hello {{ .name }}"
+  options = { missing_key = "error" }
+}`,
+				ExpectError: regexp.MustCompile(`map has no entry for key "name"`),
+			},
+			{
+				Config: `
+data "gotter_template" "test" {
+  text    = "hello"
+  options = { engine = "jinja" }
+}`,
+				ExpectError: regexp.MustCompile(`option "engine" must be one of go, mustache, got "jinja"`),
+			},
+		},
+	})
+}
+
+func TestGotterProviderFileResource(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "nested", "test.txt")
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+			"gotter": providerserver.NewProtocol6WithError(New("dev")()),
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+resource "gotter_file" "test" {
+  path = %q
+  text = "hello {{ .name }}"
+  data = { name = "world" }
+}`, file),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("gotter_file.test", tfjsonpath.New("file_permission"), knownvalue.StringExact("0644")),
+					statecheck.ExpectKnownValue("gotter_file.test", tfjsonpath.New("sha256"), knownvalue.StringExact("b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9")),
+				},
+			},
+			{
+				ImportState:                          true,
+				ImportStateId:                        file,
+				ImportStateVerify:                    true,
+				ImportStateVerifyIdentifierAttribute: "path",
+				ImportStateVerifyIgnore:              []string{"data", "text"},
+				ResourceName:                         "gotter_file.test",
+			},
+			{
+				Config: fmt.Sprintf(`
+resource "gotter_file" "test" {
+  path            = %q
+  text            = "hello {{ .name }}"
+  data            = { name = "world" }
+  file_permission = "644"
+}`, file),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("gotter_file.test", tfjsonpath.New("file_permission"), knownvalue.StringExact("644")),
+				},
+			},
+			{
+				Config: fmt.Sprintf(`
+resource "gotter_file" "test" {
+  path    = %q
+  text    = "hello <%% .name %%>"
+  data    = { name = "world" }
+  options = { left_delim = "<%%", right_delim = "%%>", layers = [{ name = "layers" }] }
+}`, file),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("gotter_file.test", tfjsonpath.New("sha256"), knownvalue.StringExact("eba15cf954991012cd4a72d9152b5cedbb6b403b7f094d4597be8dc1ad1cf005")),
+				},
+			},
+			{
+				Config: fmt.Sprintf(`
+resource "gotter_file" "test" {
+  path = %q
+  text = "hello {{ .name }}"
+  data = { name = "world" }
+}`, file),
+				PreConfig: func() {
+					if err := os.WriteFile(file, []byte("edited"), 0o644); err != nil {
+						t.Fatal(err)
+					}
+				},
+				ExpectError: regexp.MustCompile("modified outside of Terraform"),
+			},
+		},
+	})
+}
+
+func TestGotterProviderTemplateEphemeralResource(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+			"gotter": providerserver.NewProtocol6WithError(New("dev")()),
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: `
+ephemeral "gotter_template" "secret" {
+  text = "password={{ .password }}"
+  data = { password = "hunter2" }
+}
+
+ephemeral "gotter_template" "test" {
+  text = "{{ .config }}"
+  data = { config = ephemeral.gotter_template.secret.rendered }
+}`,
+			},
+			{
+				Config: `
+ephemeral "gotter_template" "test" {
+  text    = "<% .greeting %> <% .name %>"
+  data    = { greeting = "hello", name = "world" }
+  options = { left_delim = "<%", right_delim = "%>", layers = [{ name = "layers" }] }
+}`,
+			},
+			{
+				Config: `
+ephemeral "gotter_template" "test" {
+  text    = "hello"
+  options = { engine = "jinja" }
+}`,
+				ExpectError: regexp.MustCompile(`option "engine" must be one of go, mustache, got "jinja"`),
+			},
+			{
+				Config: `
+ephemeral "gotter_template" "test" {
+  text = "{{ .password "
+}`,
+				ExpectError: regexp.MustCompile("Invalid template"),
+			},
+		},
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_10_0),
+		},
+	})
+}
+
+func TestGotterProviderDirectoryResource(t *testing.T) {
+	source, destination := t.TempDir(), filepath.Join(t.TempDir(), "out")
+
+	for name, text := range map[string]string{
+		"{{ .name }}/main.go":              "package {{ .name }}",
+		"{{ if .docs }}README.md{{ end }}": "# {{ .name }}",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(source, name)), 0o755); err != nil {
+			t.Fatal(err)
+		} else if err := os.WriteFile(filepath.Join(source, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := func(name string, docs bool) string {
+		return fmt.Sprintf(`
+resource "gotter_directory" "test" {
+  source      = %q
+  destination = %q
+  data        = { name = %q, docs = %t }
+}`, source, destination, name, docs)
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+			"gotter": providerserver.NewProtocol6WithError(New("dev")()),
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: config("foo", true),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("gotter_directory.test", tfjsonpath.New("manifest"), knownvalue.MapExact(map[string]knownvalue.Check{
+						"README.md":   knownvalue.StringExact("307079195e585dee13f0685ccd6d4b2da066ade7d971e44567fefb5ad976a68f"),
+						"foo/main.go": knownvalue.NotNull(),
+					})),
+				},
+			},
+			{
+				Config: config("bar", false),
+				Check: func(_ *terraform.State) error {
+					for _, name := range []string{"README.md", "foo"} {
+						if _, err := os.Stat(filepath.Join(destination, name)); !os.IsNotExist(err) {
+							return fmt.Errorf("expected %q to be pruned", name)
+						}
+					}
+
+					return nil
+				},
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("gotter_directory.test", tfjsonpath.New("manifest"), knownvalue.MapExact(map[string]knownvalue.Check{
+						"bar/main.go": knownvalue.NotNull(),
+					})),
+				},
+			},
+			{
+				Config: fmt.Sprintf(`
+resource "gotter_directory" "test" {
+  source          = %q
+  destination     = %q
+  data            = { name = "bar", docs = false }
+  file_permission = "4755"
+}`, source, destination),
+				ExpectError: regexp.MustCompile(`"4755" sets the setuid, setgid or sticky bits`),
+			},
+		},
+	})
+}
+
+func TestGotterProviderDirectoryResourceOutside(t *testing.T) {
+	source, destination := t.TempDir(), filepath.Join(t.TempDir(), "out")
+
+	if err := os.WriteFile(filepath.Join(source, "{{ .name }}"), []byte("escaped"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, value := range map[string]string{
+		"nested": "nested/../../escaped.txt",
+		"parent": "../escaped.txt",
+	} {
+		t.Run(name, func(t *testing.T) {
+			resource.UnitTest(t, resource.TestCase{
+				ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+					"gotter": providerserver.NewProtocol6WithError(New("dev")()),
+				},
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`
+resource "gotter_directory" "test" {
+  source      = %q
+  destination = %q
+  data        = { name = %q }
+}`, source, destination, value),
+						ExpectError: regexp.MustCompile(`is not a path within the destination directory`),
+					},
+				},
+			})
+		})
+	}
+}
+
+func TestGotterProviderGlob(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, text := range map[string]string{
+		"main.tmpl":             `[{{ template "header" . }}|{{ template "partials/footer.tmpl" . }}]`,
+		"partials/header.tmpl":  `{{ define "header" }}header {{ .name }}{{ end }}`,
+		"partials/footer.tmpl":  `footer {{ .name }}`,
+		"partials/ignored.json": `{{ .ignored }`,
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+			t.Fatal(err)
+		} else if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, test := range map[string]struct {
+		check knownvalue.Check
+		value string
+	}{
+		"execute_file_with_nested_path": {
+			check: knownvalue.StringExact("footer test"),
+			value: fmt.Sprintf(`provider::gotter::execute_file(%q, { name = "test" })`, filepath.Join(dir, "partials", "footer.tmpl")),
+		},
+		"execute_glob_with_partials": {
+			check: knownvalue.StringExact("[header test|footer test]"),
+			value: fmt.Sprintf(`provider::gotter::execute_glob(%q, "main.tmpl", { name = "test" })`, filepath.Join(dir, "**", "*.tmpl")),
+		},
+		"execute_glob_with_entry": {
+			check: knownvalue.StringExact("header test"),
+			value: fmt.Sprintf(`provider::gotter::execute_glob(%q, "header", { name = "test" })`, filepath.Join(dir, "partials", "*.tmpl")),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resource.UnitTest(t, resource.TestCase{
+				ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+					"gotter": providerserver.NewProtocol6WithError(New("dev")()),
+				},
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`output "test" { value = %s }`, test.value),
+						ConfigStateChecks: []statecheck.StateCheck{
+							statecheck.ExpectKnownOutputValue("test", test.check),
+						},
+					},
+				},
+				TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+					tfversion.SkipBelow(tfversion.Version1_8_0),
+				},
+			})
+		})
+	}
+}
+
+func TestGotterProviderOptions(t *testing.T) {
+	for name, test := range map[string]struct {
+		check   knownvalue.Check
+		error   *regexp.Regexp
+		options string
+		text    string
+	}{
+		"delims": {
+			check:   knownvalue.StringExact(`{{ .Values.name | default "chart" }} test`),
+			options: `{ left_delim = "[[", right_delim = "]]" }`,
+			text:    `{{ .Values.name | default "chart" }} [[ .name ]]`,
+		},
+		"delims_with_partial": {
+			check:   knownvalue.StringExact("<test>"),
+			options: `{ left_delim = "[[", right_delim = "]]" }, { partials = { inner = "<[[ .name ]]>" } }`,
+			text:    `[[ template "inner" . ]]`,
+		},
+		"escape_json": {
+			check:   knownvalue.StringExact(`{"name": "say \"hi\"\n", "port": 443, "tags": ["a","b"], "greeting": "hello say \"hi\"\n"}`),
+			options: `{ escape = "json" }`,
+			text:    `{"name": {{ .message }}, "port": {{ .port }}, "tags": {{ .tags }}, "greeting": "hello {{ .message }}"}`,
+		},
+		"escape_json_with_branches": {
+			error:   regexp.MustCompile(`{{if}} branches end in different contexts`),
+			options: `{ escape = "json" }`,
+			text:    `{"name": "{{ if .message }}{{ .message }}"{{ end }}}`,
+		},
+		"escape_json_with_include": {
+			check:   knownvalue.StringExact(`{"labels": {"name": "say \"hi\"\n"}}`),
+			options: `{ escape = "json" }`,
+			text:    `{"labels": {{ include "labels" . }}}{{ define "labels" }}{"name": {{ .message }}}{{ end }}`,
+		},
+		"escape_json_with_json": {
+			check:   knownvalue.StringExact(`{"tags": ["a","b"], "text": "[\"a\",\"b\"]"}`),
+			options: `{ escape = "json" }`,
+			text:    `{"tags": {{ json .tags }}, "text": "{{ json .tags }}"}`,
+		},
+		"escape_json_with_missing_key": {
+			check:   knownvalue.StringExact(`{"name": null, "greeting": "hello "}`),
+			options: `{ escape = "json", missing_key = "zero" }`,
+			text:    `{"name": {{ .missing }}, "greeting": "hello {{ .missing }}"}`,
+		},
+		"escape_yaml": {
+			check:   knownvalue.StringExact("name: \"say \\\"hi\\\"\\n\"\nport: 443\nenabled: \"yes\"\nimage: repo/nginx:1.0\nquoted: 'it''s'\ntags: [\"a\",\"b\"]\n"),
+			options: `{ escape = "yaml" }`,
+			text:    "name: {{ .message }}\nport: {{ .port }}\nenabled: {{ .enabled }}\nimage: repo/{{ .image }}:1.0\nquoted: '{{ .quoted }}'\ntags: {{ .tags }}\n",
+		},
+		"escape_yaml_with_plain_scalar": {
+			error:   regexp.MustCompile(`cannot be embedded in a YAML plain scalar`),
+			options: `{ escape = "yaml" }`,
+			text:    `greeting: hello {{ .message }}`,
+		},
+		"escape_invalid": {
+			error:   regexp.MustCompile(`option "escape" must be one of json, none, yaml`),
+			options: `{ escape = "xml" }`,
+			text:    `{{ .message }}`,
+		},
+		"engine_mustache": {
+			check:   knownvalue.StringExact("test: say &quot;hi&quot;\nit's[a][b]\n"),
+			options: `{ engine = "mustache" }`,
+			text:    "{{#enabled}}\n{{name}}: {{message}}{{{quoted}}}{{#tags}}[{{.}}]{{/tags}}\n{{/enabled}}\n",
+		},
+		"engine_mustache_with_partials": {
+			check:   knownvalue.StringExact("  - a\n  - b\n"),
+			options: `{ engine = "mustache", partials = { item = "- {{.}}\n" } }`,
+			text:    "{{#tags}}\n  {{> item}}\n{{/tags}}\n",
+		},
+		"engine_mustache_with_delims": {
+			check:   knownvalue.StringExact("{{ name }} test"),
+			options: `{ engine = "mustache", left_delim = "<%", right_delim = "%>" }`,
+			text:    `{{ name }} <% name %>`,
+		},
+		"engine_mustache_with_missing_key_error": {
+			error:   regexp.MustCompile(`missing variable "missing"`),
+			options: `{ engine = "mustache", missing_key = "error" }`,
+			text:    `{{ missing }}`,
+		},
+		"engine_mustache_with_template": {
+			error:   regexp.MustCompile(`option "template" is not supported by the mustache engine`),
+			options: `{ engine = "mustache", template = "inner" }`,
+			text:    `{{ name }}`,
+		},
+		"engine_invalid": {
+			error:   regexp.MustCompile(`option "engine" must be one of go, mustache`),
+			options: `{ engine = "jinja" }`,
+			text:    `{{ name }}`,
+		},
+		"missing_key_default": {
+			check:   knownvalue.StringExact("<no value>"),
+			options: `{ missing_key = "default" }`,
+			text:    `{{ .missing }}`,
+		},
+		"missing_key_zero": {
+			check:   knownvalue.StringExact("[]"),
+			options: `{ missing_key = "zero" }`,
+			text:    `[{{ .missing }}]`,
+		},
+		"missing_key_error": {
+			error:   regexp.MustCompile(`map has no entry for key "missing"`),
+			options: `{ missing_key = "error" }`,
+			text:    `{{ .missing }}`,
+		},
+		"missing_key_invalid": {
+			error:   regexp.MustCompile(`option "missing_key" must be one of default, error, zero`),
+			options: `{ missing_key = "invalid" }`,
+			text:    `{{ .missing }}`,
+		},
+		"unsupported_option": {
+			error:   regexp.MustCompile(`unsupported option "delims"`),
+			options: `{ delims = "[[ ]]" }`,
+			text:    `{{ .name }}`,
+		},
+		"template": {
+			check:   knownvalue.StringExact("entry test"),
+			options: `{ template = "entry" }`,
+			text:    `{{ define "entry" }}entry {{ .name }}{{ end }}root`,
+		},
+		"template_with_later_options": {
+			check:   knownvalue.StringExact("second"),
+			options: `{ template = "first" }, { template = "second" }`,
+			text:    `{{ define "first" }}first{{ end }}{{ define "second" }}second{{ end }}`,
+		},
+		"template_undefined": {
+			error:   regexp.MustCompile(`template "entry" is not defined`),
+			options: `{ template = "entry" }`,
+			text:    `root`,
+		},
+		"template_with_partial": {
+			check:   knownvalue.StringExact("app=test"),
+			options: `{ partials = { labels = "app={{ .name }}" } }`,
+			text:    `{{ template "labels" . }}`,
+		},
+		"trim_blocks": {
+			check:   knownvalue.StringExact("tags:\n    - a\n    - b\n  "),
+			options: `{ trim_blocks = true }`,
+			text:    "tags:\n  {{ range .tags }}\n  - {{ . }}\n  {{ end }}\n",
+		},
+		"lstrip_blocks": {
+			check:   knownvalue.StringExact("tags:\n\n  - a\n\n  - b\n\n"),
+			options: `{ lstrip_blocks = true }`,
+			text:    "tags:\n  {{ range .tags }}\n  - {{ . }}\n  {{ end }}\n",
+		},
+		"trim_blocks_with_lstrip_blocks": {
+			check:   knownvalue.StringExact("spec:\n  name: test\n  tags:\n  - a\n  - b\n"),
+			options: `{ lstrip_blocks = true, trim_blocks = true }`,
+			text:    "spec:\n  {{ if .enabled }}\n  {{/* the name */}}\n  name: {{ .name }}\n  {{ end }}\n  tags:\n  {{ range .tags }}\n  - {{ . }}\n  {{ end }}\n",
+		},
+		"trim_blocks_with_inline_actions": {
+			check:   knownvalue.StringExact("name: test\nenabled: true\n"),
+			options: `{ lstrip_blocks = true, trim_blocks = true }`,
+			text:    "name: {{ .name }}\nenabled: {{ if .enabled }}true{{ end }}\n",
+		},
+		"trim_blocks_with_partial": {
+			check:   knownvalue.StringExact("- a\n- b\n"),
+			options: `{ lstrip_blocks = true, partials = { item = "  {{ with . }}\n- {{ . }}\n  {{ end }}\n" }, trim_blocks = true }`,
+			text:    `{{ range .tags }}{{ template "item" . }}{{ end }}`,
+		},
+		"block_without_partial": {
+			check:   knownvalue.StringExact("[default]"),
+			options: `{ partials = {} }`,
+			text:    `[{{ block "body" . }}default{{ end }}]`,
+		},
+		"block_with_partial": {
+			check:   knownvalue.StringExact("[body test]"),
+			options: `{ partials = { body = "body {{ .name }}" } }`,
+			text:    `[{{ block "body" . }}default{{ end }}]`,
+		},
+		"partial_with_partial": {
+			check:   knownvalue.StringExact("<test>"),
+			options: `{ partials = { inner = "{{ .name }}", outer = "<{{ template \"inner\" . }}>" } }`,
+			text:    `{{ template "outer" . }}`,
+		},
+		"partial_with_collision": {
+			error:   regexp.MustCompile(`partial "labels" redefines template "extra"`),
+			options: `{ partials = { base = "{{ define \"extra\" }}{{ end }}", labels = "{{ define \"extra\" }}{{ end }}" } }`,
+			text:    `{{ template "labels" . }}`,
+		},
+		"partials_with_collision": {
+			error:   regexp.MustCompile(`partial "labels" is defined more than once`),
+			options: `{ partials = { labels = "a" } }, { partials = { labels = "b" } }`,
+			text:    `{{ template "labels" . }}`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resource.UnitTest(t, resource.TestCase{
+				ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+					"gotter": providerserver.NewProtocol6WithError(New("dev")()),
+				},
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`output "test" { value = provider::gotter::execute(%q, { name = "test", enabled = "yes", image = "nginx", message = "say \"hi\"\n", port = 443, quoted = "it's", tags = ["a", "b"] }, %s) }`, test.text, test.options),
+						ConfigStateChecks: []statecheck.StateCheck{
+							statecheck.ExpectKnownOutputValue("test", test.check),
+						},
+						ExpectError: test.error,
+					},
+				},
+				TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+					tfversion.SkipBelow(tfversion.Version1_8_0),
+				},
+			})
+		})
+	}
+}
+
+func TestGotterProviderHTML(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "page.html")
+
+	if err := os.WriteFile(file, []byte(`<h1>{{ .name }}</h1>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, test := range map[string]struct {
+		check knownvalue.Check
+		error *regexp.Regexp
+		value string
+	}{
+		"html": {
+			check: knownvalue.StringExact("<p>&lt;b&gt;Tom &amp; Jerry&lt;/b&gt;</p>"),
+			value: `provider::gotter::execute_html("<p>{{ .name }}</p>", { name = "<b>Tom & Jerry</b>" })`,
+		},
+		"html_file": {
+			check: knownvalue.StringExact("<h1>&lt;b&gt;Tom &amp; Jerry&lt;/b&gt;</h1>"),
+			value: fmt.Sprintf(`provider::gotter::execute_html_file(%q, { name = "<b>Tom & Jerry</b>" })`, file),
+		},
+		"js": {
+			check: knownvalue.StringExact(`<script>var name = "\u003cb\u003e";</script>`),
+			value: `provider::gotter::execute_html("<script>var name = {{ .name }};</script>", { name = "<b>" })`,
+		},
+		"url": {
+			check: knownvalue.StringExact(`<a href="/search?q=Tom%20%26%20Jerry">search</a>`),
+			value: `provider::gotter::execute_html("<a href=\"/search?q={{ .name }}\">search</a>", { name = "Tom & Jerry" })`,
+		},
+		"url_unsafe": {
+			check: knownvalue.StringExact(`<a href="#ZgotmplZ">link</a>`),
+			value: `provider::gotter::execute_html("<a href=\"{{ .url }}\">link</a>", { url = "javascript:alert(1)" })`,
+		},
+		"css_unsafe": {
+			check: knownvalue.StringExact(`<p style="color: ZgotmplZ">`),
+			value: `provider::gotter::execute_html("<p style=\"color: {{ .color }}\">", { color = "red; background: url(evil)" })`,
+		},
+		"functions": {
+			check: knownvalue.StringExact("<p>TOM 4</p>"),
+			value: `provider::gotter::execute_html("<p>{{ upper .name }} {{ add .count 1 }}</p>", { name = "tom", count = 3 })`,
+		},
+		"escape_error": {
+			error: regexp.MustCompile(`ends in a non-text context`),
+			value: `provider::gotter::execute_html("<a href=\"{{ .url }}", { url = "/" })`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resource.UnitTest(t, resource.TestCase{
+				ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+					"gotter": providerserver.NewProtocol6WithError(New("dev")()),
+				},
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`output "test" { value = %s }`, test.value),
+						ConfigStateChecks: []statecheck.StateCheck{
+							statecheck.ExpectKnownOutputValue("test", test.check),
+						},
+						ExpectError: test.error,
+					},
+				},
+				TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+					tfversion.SkipBelow(tfversion.Version1_8_0),
+				},
+			})
+		})
+	}
+}
+
+func TestGotterProviderEnvsubst(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.conf")
+
+	if err := os.WriteFile(file, []byte("listen ${server.host}:${server.port:-80}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, test := range map[string]struct {
+		check knownvalue.Check
+		error *regexp.Regexp
+		value string
+	}{
+		"variable": {
+			check: knownvalue.StringExact("hello world"),
+			value: `provider::gotter::execute_envsubst("hello $${NAME}", { NAME = "world" })`,
+		},
+		"variable_missing": {
+			check: knownvalue.StringExact("hello "),
+			value: `provider::gotter::execute_envsubst("hello $${NAME}", {})`,
+		},
+		"variable_nested": {
+			check: knownvalue.StringExact("example.com:443 b"),
+			value: `provider::gotter::execute_envsubst("$${server.host}:$${server.port} $${tags.1}", { server = { host = "example.com", port = 443 }, tags = ["a", "b"] })`,
+		},
+		"default": {
+			check: knownvalue.StringExact("hello stranger, hello world"),
+			value: `provider::gotter::execute_envsubst("hello $${NAME:-stranger}, hello $${EMPTY:-$${OTHER}}", { NAME = null, EMPTY = "", OTHER = "world" })`,
+		},
+		"default_without_colon": {
+			check: knownvalue.StringExact("hello stranger, hello "),
+			value: `provider::gotter::execute_envsubst("hello $${NAME-stranger}, hello $${EMPTY-stranger}", { NAME = null, EMPTY = "" })`,
+		},
+		"error": {
+			error: regexp.MustCompile(`line 1: NAME: is required`),
+			value: `provider::gotter::execute_envsubst("hello $${NAME:?is required}", { NAME = "" })`,
+		},
+		"error_without_colon": {
+			error: regexp.MustCompile(`line 1: NAME: parameter not set`),
+			value: `provider::gotter::execute_envsubst("hello $${EMPTY?}$${NAME?}", { EMPTY = "" })`,
+		},
+		"escape": {
+			check: knownvalue.StringExact("${NAME} is world"),
+			value: `provider::gotter::execute_envsubst(join("", ["$$", "{NAME} is $${NAME}"]), { NAME = "world" })`,
+		},
+		"file": {
+			check: knownvalue.StringExact("listen example.com:80\n"),
+			value: fmt.Sprintf(`provider::gotter::execute_envsubst_file(%q, { server = { host = "example.com" } })`, file),
+		},
+		"strict": {
+			error: regexp.MustCompile(`line 1: variable "NAME" is not set`),
+			value: `provider::gotter::execute_envsubst("hello $${NAME}", {}, { strict = true })`,
+		},
+		"strict_with_default": {
+			check: knownvalue.StringExact("hello stranger"),
+			value: `provider::gotter::execute_envsubst("hello $${NAME:-stranger}", {}, { strict = true })`,
+		},
+		"unclosed": {
+			error: regexp.MustCompile(`line 1: unclosed placeholder "NAME"`),
+			value: `provider::gotter::execute_envsubst("hello $${NAME", {})`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resource.UnitTest(t, resource.TestCase{
+				ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+					"gotter": providerserver.NewProtocol6WithError(New("dev")()),
+				},
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`output "test" { value = %s }`, test.value),
+						ConfigStateChecks: []statecheck.StateCheck{
+							statecheck.ExpectKnownOutputValue("test", test.check),
+						},
+						ExpectError: test.error,
+					},
+				},
+				TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+					tfversion.SkipBelow(tfversion.Version1_8_0),
+				},
+			})
+		})
+	}
+}
+
+func TestGotterProviderLayouts(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, text := range map[string]string{
+		"a.tmpl":              `{{/* extends "b.tmpl" */}}`,
+		"b.tmpl":              `{{/* extends "a.tmpl" */}}`,
+		"child.tmpl":          "{{/* extends \"layouts/page.tmpl\" */}}\n{{ define \"body\" }}child {{ .name }}{{ end }}",
+		"layouts/base.tmpl":   `<{{ block "title" . }}base{{ end }}|{{ block "body" . }}base{{ end }}>`,
+		"layouts/page.tmpl":   `{{/* extends "base.tmpl" */}}{{ define "title" }}page{{ end }}{{ define "body" }}page{{ end }}`,
+		"layouts/broken.tmpl": `{{/* extends "missing.tmpl" */}}`,
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+			t.Fatal(err)
+		} else if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, test := range map[string]struct {
+		check knownvalue.Check
+		error *regexp.Regexp
+		value string
+	}{
+		"directive": {
+			check: knownvalue.StringExact("<page|child test>"),
+			value: fmt.Sprintf(`provider::gotter::execute_file(%q, { name = "test" })`, filepath.Join(dir, "child.tmpl")),
+		},
+		"directive_with_cycle": {
+			error: regexp.MustCompile(`b\.tmpl" extends ".*a\.tmpl", which introduces a cycle`),
+			value: fmt.Sprintf(`provider::gotter::execute_file(%q, {})`, filepath.Join(dir, "a.tmpl")),
+		},
+		"directive_with_missing_layout": {
+			error: regexp.MustCompile(`broken\.tmpl" extends ".*missing\.tmpl"`),
+			value: fmt.Sprintf(`provider::gotter::execute_file(%q, {})`, filepath.Join(dir, "layouts", "broken.tmpl")),
+		},
+		"option": {
+			check: knownvalue.StringExact("<base|child test>"),
+			value: fmt.Sprintf(`provider::gotter::execute_file(%q, { name = "test" }, { extends = "layouts/base.tmpl" })`, filepath.Join(dir, "child.tmpl")),
+		},
+		"option_with_text": {
+			check: knownvalue.StringExact("<page|text test>"),
+			value: fmt.Sprintf(`provider::gotter::execute("{{ define \"body\" }}text {{ .name }}{{ end }}", { name = "test" }, { extends = %q })`, filepath.Join(dir, "layouts", "page.tmpl")),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resource.UnitTest(t, resource.TestCase{
+				ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+					"gotter": providerserver.NewProtocol6WithError(New("dev")()),
+				},
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`output "test" { value = %s }`, test.value),
+						ConfigStateChecks: []statecheck.StateCheck{
+							statecheck.ExpectKnownOutputValue("test", test.check),
+						},
+						ExpectError: test.error,
+					},
+				},
+				TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+					tfversion.SkipBelow(tfversion.Version1_8_0),
+				},
+			})
+		})
+	}
+}
+
+func TestGotterProviderInclude(t *testing.T) {
+	for name, test := range map[string]struct {
+		check knownvalue.Check
+		error *regexp.Regexp
+		value string
+	}{
+		"include": {
+			check: knownvalue.StringExact("metadata:\n  labels:\n    app: test\n    tier: web"),
+			value: `provider::gotter::execute("{{ define \"labels\" }}app: {{ .name }}\ntier: web{{ end }}metadata:\n  labels:\n{{ include \"labels\" . | indent 4 }}", { name = "test" })`,
+		},
+		"include_with_width": {
+			check: knownvalue.StringExact("  a\n  b ab [a b-c]"),
+			value: `provider::gotter::execute("{{ indent .width \"a\\nb\" }} {{ truncate .width \"abc\" }} {{ split_n \"-\" .width \"a-b-c\" }}", { width = 2 })`,
+		},
+		"include_with_json": {
+			check: knownvalue.StringExact(`"app: test"`),
+			value: `provider::gotter::execute("{{ define \"labels\" }}app: {{ .name }}{{ end }}{{ include \"labels\" . | json }}", { name = "test" })`,
+		},
+		"include_with_partial": {
+			check: knownvalue.StringExact("[TEST]"),
+			value: `provider::gotter::execute("[{{ include \"name\" . | upper }}]", { name = "test" }, { partials = { name = "{{ .name }}" } })`,
+		},
+		"include_recursive": {
+			error: regexp.MustCompile(`include "loop" exceeds the maximum depth of 100`),
+			value: `provider::gotter::execute("{{ define \"loop\" }}{{ include \"loop\" . }}{{ end }}{{ include \"loop\" . }}", {})`,
+		},
+		"include_undefined": {
+			error: regexp.MustCompile(`template "missing" is not defined`),
+			value: `provider::gotter::execute("{{ include \"missing\" . }}", {})`,
+		},
+		"tpl": {
+			check: knownvalue.StringExact("hello TEST"),
+			value: `provider::gotter::execute("{{ tpl .greeting . }}", { greeting = "hello {{ .name | upper }}", name = "test" })`,
+		},
+		"tpl_with_include": {
+			check: knownvalue.StringExact("<test>"),
+			value: `provider::gotter::execute("{{ define \"name\" }}<{{ .name }}>{{ end }}{{ tpl .text . }}", { name = "test", text = "{{ include \"name\" . }}" })`,
+		},
+		"func": {
+			check: knownvalue.StringExact("WEB.EXAMPLE.COM"),
+			value: `provider::gotter::execute("{{ define \"func:fqdn\" }}{{ index $.args 0 }}.{{ index $.args 1 }}{{ end }}{{ fqdn .name .zone | upper }}", { name = "web", zone = "example.com" })`,
+		},
+		"func_with_number": {
+			check: knownvalue.StringExact("444"),
+			value: `provider::gotter::execute("{{ define \"func:inc\" }}{{ add (index $.args 0) 1 }}{{ end }}{{ inc .port }}", { port = 443 })`,
+		},
+		"func_with_partial": {
+			check: knownvalue.StringExact("hello web"),
+			value: `provider::gotter::execute("{{ greet .name }}", { name = "web" }, { partials = { funcs = "{{ define \"func:greet\" }}hello {{ index $.args 0 }}{{ end }}" } })`,
+		},
+		"func_recursive": {
+			error: regexp.MustCompile(`function "loop" exceeds the maximum depth of 100`),
+			value: `provider::gotter::execute("{{ define \"func:loop\" }}{{ loop }}{{ end }}{{ loop }}", {})`,
+		},
+		"tpl_recursive": {
+			error: regexp.MustCompile(`tpl exceeds the maximum depth of 100`),
+			value: `provider::gotter::execute("{{ tpl .text . }}", { text = "{{ tpl .text . }}" })`,
+		},
+		"include_html": {
+			check: knownvalue.StringExact("<ul><li>a&lt;b</li></ul>"),
+			value: `provider::gotter::execute_html("{{ define \"item\" }}<li>{{ . }}</li>{{ end }}<ul>{{ include \"item\" .name }}</ul>", { name = "a<b" })`,
+		},
+		"func_html": {
+			check: knownvalue.StringExact("<a href=\"/web\">web &amp; db</a>"),
+			value: `provider::gotter::execute_html("{{ define \"func:link\" }}<a href=\"/{{ index $.args 0 }}\">{{ index $.args 1 }}</a>{{ end }}{{ link .name .label }}", { name = "web", label = "web & db" })`,
+		},
+		"tpl_html": {
+			error: regexp.MustCompile(`tpl is not supported by execute_html`),
+			value: `provider::gotter::execute_html("{{ tpl .text . }}", { text = "hello" })`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resource.UnitTest(t, resource.TestCase{
+				ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+					"gotter": providerserver.NewProtocol6WithError(New("dev")()),
+				},
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`output "test" { value = %s }`, test.value),
+						ConfigStateChecks: []statecheck.StateCheck{
+							statecheck.ExpectKnownOutputValue("test", test.check),
+						},
+						ExpectError: test.error,
+					},
+				},
+				TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+					tfversion.SkipBelow(tfversion.Version1_8_0),
+				},
+			})
+		})
+	}
+}
+
+func TestGotterProviderPlugins(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, script := range map[string]string{
+		"echo.sh":    `read -r request; printf '{"result": %s}' "$request"`,
+		"error.sh":   `cat >/dev/null; echo '{"error": "no such host"}'`,
+		"exit.sh":    `cat >/dev/null; echo "pid $$ starting" >&2; echo boom >&2; exit 3`,
+		"invalid.sh": `cat >/dev/null; echo 'not json'`,
+		"slow.sh":    `cat >/dev/null; sleep 5`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Setenv("GOTTER_PLUGIN_ALLOWLIST", filepath.Join(dir, "*.sh"))
+
+	plugin := func(name string, script string, extra string) string {
+		return fmt.Sprintf(`{ plugins = { %s = { program = [%q]%s } } }`, name, filepath.Join(dir, script), extra)
+	}
+
+	for name, test := range map[string]struct {
+		check knownvalue.Check
+		error *regexp.Regexp
+		value string
+	}{
+		"result": {
+			check: knownvalue.StringExact("echo test 443"),
+			value: fmt.Sprintf(`provider::gotter::execute("{{ $r := echo .name .port }}{{ $r.function }} {{ index $r.args 0 }} {{ index $r.args 1 }}", { name = "test", port = 443 }, %s)`, plugin("echo", "echo.sh", "")),
+		},
+		"result_with_number": {
+			check: knownvalue.StringExact("444"),
+			value: fmt.Sprintf(`provider::gotter::execute("{{ add (index (echo .port).args 0) 1 }}", { port = 443 }, %s)`, plugin("echo", "echo.sh", "")),
+		},
+		"error": {
+			error: regexp.MustCompile(`plugin "lookup": no such host`),
+			value: fmt.Sprintf(`provider::gotter::execute("{{ lookup .name }}", { name = "test" }, %s)`, plugin("lookup", "error.sh", "")),
+		},
+		"exit": {
+			error: regexp.MustCompile(`plugin "fail" exited with code 3: boom`),
+			value: fmt.Sprintf(`provider::gotter::execute("{{ fail }}", {}, %s)`, plugin("fail", "exit.sh", "")),
+		},
+		"invalid_response": {
+			error: regexp.MustCompile(`plugin "invalid" returned an invalid response`),
+			value: fmt.Sprintf(`provider::gotter::execute("{{ invalid }}", {}, %s)`, plugin("invalid", "invalid.sh", "")),
+		},
+		"timeout": {
+			error: regexp.MustCompile(`plugin "slow" timed out after 100ms`),
+			value: fmt.Sprintf(`provider::gotter::execute("{{ slow }}", {}, %s)`, plugin("slow", "slow.sh", `, timeout = "100ms"`)),
+		},
+		"invalid_timeout": {
+			error: regexp.MustCompile(`"timeout" must be a duration`),
+			value: fmt.Sprintf(`provider::gotter::execute("{{ slow }}", {}, %s)`, plugin("slow", "slow.sh", `, timeout = "soon"`)),
+		},
+		"not_allowed": {
+			error: regexp.MustCompile(`is not allowed; add it to GOTTER_PLUGIN_ALLOWLIST`),
+			value: `provider::gotter::execute("{{ sh }}", {}, { plugins = { sh = { program = ["/bin/sh", "-c", "echo"] } } })`,
+		},
+		"invalid_name": {
+			error: regexp.MustCompile(`option "plugins" has invalid function name "my-func"`),
+			value: fmt.Sprintf(`provider::gotter::execute("", {}, { plugins = { "my-func" = { program = [%q] } } })`, filepath.Join(dir, "echo.sh")),
+		},
+		"mustache": {
+			error: regexp.MustCompile(`option "plugins" is not supported by the mustache engine`),
+			value: fmt.Sprintf(`provider::gotter::execute("", {}, { engine = "mustache", plugins = { echo = { program = [%q] } } })`, filepath.Join(dir, "echo.sh")),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resource.UnitTest(t, resource.TestCase{
+				ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+					"gotter": providerserver.NewProtocol6WithError(New("dev")()),
+				},
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`output "test" { value = %s }`, test.value),
+						ConfigStateChecks: []statecheck.StateCheck{
+							statecheck.ExpectKnownOutputValue("test", test.check),
+						},
+						ExpectError: test.error,
+					},
+				},
+				TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+					tfversion.SkipBelow(tfversion.Version1_8_0),
+				},
+			})
+		})
+	}
+}
+
+func TestGotterProviderObject(t *testing.T) {
+	for name, test := range map[string]struct {
+		check knownvalue.Check
+		error *regexp.Regexp
+		value string
+	}{
+		"emit": {
+			check: knownvalue.ObjectExact(map[string]knownvalue.Check{
+				"metadata": knownvalue.ObjectExact(map[string]knownvalue.Check{
+					"name": knownvalue.StringExact("test"),
+				}),
+				"spec": knownvalue.ObjectExact(map[string]knownvalue.Check{
+					"enabled": knownvalue.Bool(true),
+					"port":    knownvalue.Int64Exact(444),
+				}),
+			}),
+			value: `provider::gotter::execute_object("{{ emit \"metadata.name\" .name }}\n{{ emit \"spec.port\" (add .port 1) }}\n{{ emit \"spec.enabled\" true }}", { name = "test", port = 443 })`,
+		},
+		"append": {
+			check: knownvalue.ObjectExact(map[string]knownvalue.Check{
+				"tags": knownvalue.TupleExact([]knownvalue.Check{
+					knownvalue.StringExact("A"),
+					knownvalue.StringExact("B"),
+				}),
+			}),
+			value: `provider::gotter::execute_object("{{ range .tags }}{{ append \"tags\" (upper .) }}{{ end }}", { tags = ["a", "b"] })`,
+		},
+		"emit_object": {
+			check: knownvalue.ObjectExact(map[string]knownvalue.Check{
+				"copy": knownvalue.ObjectExact(map[string]knownvalue.Check{
+					"image": knownvalue.StringExact("nginx"),
+					"port":  knownvalue.Int64Exact(443),
+				}),
+				"original": knownvalue.ObjectExact(map[string]knownvalue.Check{
+					"image": knownvalue.StringExact("nginx"),
+				}),
+			}),
+			value: `provider::gotter::execute_object("{{ emit \"copy\" .container }}{{ emit \"copy.port\" 443 }}{{ emit \"original\" .container }}", { container = { image = "nginx" } })`,
+		},
+		"empty": {
+			check: knownvalue.ObjectExact(map[string]knownvalue.Check{}),
+			value: `provider::gotter::execute_object("ignored", {})`,
+		},
+		"not_an_object": {
+			error: regexp.MustCompile(`emit "name.first": "name" is not an object`),
+			value: `provider::gotter::execute_object("{{ emit \"name\" .name }}{{ emit \"name.first\" .name }}", { name = "test" })`,
+		},
+		"not_a_list": {
+			error: regexp.MustCompile(`append "name": "name" is not a list`),
+			value: `provider::gotter::execute_object("{{ emit \"name\" .name }}{{ append \"name\" .name }}", { name = "test" })`,
+		},
+		"invalid_path": {
+			error: regexp.MustCompile(`emit "spec\.\.port": path must be a dot-separated list of keys`),
+			value: `provider::gotter::execute_object("{{ emit \"spec..port\" 443 }}", {})`,
+		},
+		"mustache": {
+			error: regexp.MustCompile(`option "engine" must be "go" for execute_object`),
+			value: `provider::gotter::execute_object("", {}, { engine = "mustache" })`,
+		},
+		"execute": {
+			error: regexp.MustCompile(`emit "name" is only available in execute_object`),
+			value: `provider::gotter::execute("{{ emit \"name\" .name }}", { name = "test" })`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resource.UnitTest(t, resource.TestCase{
+				ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+					"gotter": providerserver.NewProtocol6WithError(New("dev")()),
+				},
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`output "test" { value = %s }`, test.value),
+						ConfigStateChecks: []statecheck.StateCheck{
+							statecheck.ExpectKnownOutputValue("test", test.check),
+						},
+						ExpectError: test.error,
+					},
+				},
+				TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+					tfversion.SkipBelow(tfversion.Version1_8_0),
+				},
+			})
+		})
+	}
+}
+
+func TestGotterProviderDecode(t *testing.T) {
+	for name, test := range map[string]struct {
+		check knownvalue.Check
+		error *regexp.Regexp
+		value string
+	}{
+		"json": {
+			check: knownvalue.ObjectExact(map[string]knownvalue.Check{
+				"name": knownvalue.StringExact("test"),
+				"port": knownvalue.Int64Exact(443),
+				"tags": knownvalue.TupleExact([]knownvalue.Check{
+					knownvalue.StringExact("a"),
+					knownvalue.StringExact("b"),
+				}),
+			}),
+			value: `provider::gotter::execute_json("{ \"name\": {{ json .name }}, \"port\": {{ .port }}, \"tags\": {{ json .tags }} }", { name = "test", port = 443, tags = ["a", "b"] })`,
+		},
+		"json_with_escape": {
+			check: knownvalue.ObjectExact(map[string]knownvalue.Check{
+				"message": knownvalue.StringExact("say \"hi\"\n"),
+			}),
+			value: `provider::gotter::execute_json("{ \"message\": \"{{ .message }}\" }", { message = "say \"hi\"\n" }, { escape = "json" })`,
+		},
+		"json_invalid": {
+			error: regexp.MustCompile(`(?s)not valid JSON at line 2, column 12.*written by the template at line 2, column 14:\n\n1 \| \{\n2 \|   "name": \{\{ \.name \}\}\n  \|              \^`),
+			value: `provider::gotter::execute_json("{\n  \"name\": {{ .name }}\n}", { name = "test" })`,
+		},
+		"json_invalid_partial": {
+			error: regexp.MustCompile(`(?s)not valid JSON at line 2, column 9.*written by partial "pair" at line 1, column 9`),
+			value: `provider::gotter::execute_json("{\n  {{ template \"pair\" . }}\n}", { name = "test" }, { partials = { pair = "\"a\": {{ .name }}" } })`,
+		},
+		"json_trailing": {
+			error: regexp.MustCompile(`invalid character '\}' after top-level value`),
+			value: `provider::gotter::execute_json("{}}", {})`,
+		},
+		"yaml": {
+			check: knownvalue.ObjectExact(map[string]knownvalue.Check{
+				"name": knownvalue.StringExact("test"),
+				"port": knownvalue.Int64Exact(443),
+				"tags": knownvalue.TupleExact([]knownvalue.Check{
+					knownvalue.StringExact("a"),
+					knownvalue.StringExact("b"),
+				}),
+			}),
+			value: `provider::gotter::execute_yaml("name: {{ .name }}\nport: {{ .port }}\ntags:\n{{- range .tags }}\n  - {{ . }}\n{{- end }}\n", { name = "test", port = 443, tags = ["a", "b"] })`,
+		},
+		"yaml_empty": {
+			check: knownvalue.Null(),
+			value: `provider::gotter::execute_yaml("{{ if .enabled }}enabled: true{{ end }}", { enabled = false })`,
+		},
+		"yaml_invalid": {
+			error: regexp.MustCompile(`(?s)not valid YAML at line 2: mapping values are not allowed.*written by the template at line 2, column 3`),
+			value: `provider::gotter::execute_yaml("name: {{ .name }}\n  port: {{ .port }}\n", { name = "test", port = 443 })`,
+		},
+		"yaml_duplicate_key": {
+			error: regexp.MustCompile(`not valid YAML at line 2, column 1: mapping key "name" is defined more`),
+			value: `provider::gotter::execute_yaml("name: {{ .name }}\nname: {{ .name }}\n", { name = "test" })`,
+		},
+		"yaml_multiple_documents": {
+			error: regexp.MustCompile(`expected a single document`),
+			value: `provider::gotter::execute_yaml("name: {{ .name }}\n---\nname: {{ .name }}\n", { name = "test" })`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resource.UnitTest(t, resource.TestCase{
+				ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+					"gotter": providerserver.NewProtocol6WithError(New("dev")()),
+				},
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`output "test" { value = %s }`, test.value),
+						ConfigStateChecks: []statecheck.StateCheck{
+							statecheck.ExpectKnownOutputValue("test", test.check),
+						},
+						ExpectError: test.error,
+					},
+				},
+				TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+					tfversion.SkipBelow(tfversion.Version1_8_0),
+				},
+			})
+		})
+	}
+}
+
+func TestGotterProviderMap(t *testing.T) {
+	for name, test := range map[string]struct {
+		check knownvalue.Check
+		error *regexp.Regexp
+		value string
+	}{
+		"map": {
+			check: knownvalue.MapExact(map[string]knownvalue.Check{
+				"host": knownvalue.StringExact("test:443"),
+				"name": knownvalue.StringExact("TEST"),
+			}),
+			value: `provider::gotter::execute_map({ host = "{{ .name }}:{{ .port }}", name = "{{ .name | upper }}" }, { name = "test", port = 443 })`,
+		},
+		"map_with_options": {
+			check: knownvalue.MapExact(map[string]knownvalue.Check{
+				"greeting": knownvalue.StringExact("hello test"),
+			}),
+			value: `provider::gotter::execute_map({ greeting = "<% template \"greet\" . %>" }, { name = "test" }, { left_delim = "<%", partials = { greet = "hello <% .name %>" }, right_delim = "%>" })`,
+		},
+		"map_with_mustache": {
+			check: knownvalue.MapExact(map[string]knownvalue.Check{
+				"name": knownvalue.StringExact("test"),
+			}),
+			value: `provider::gotter::execute_map({ name = "{{ name }}" }, { name = "test" }, { engine = "mustache" })`,
+		},
+		"empty": {
+			check: knownvalue.MapExact(map[string]knownvalue.Check{}),
+			value: `provider::gotter::execute_map({}, {})`,
+		},
+		"parse_error": {
+			error: regexp.MustCompile(`template "broken": template: :1: unclosed action`),
+			value: `provider::gotter::execute_map({ broken = "{{ .name", fine = "{{ .name }}" }, { name = "test" })`,
+		},
+		"execute_error": {
+			error: regexp.MustCompile(`template "broken": .*index out of range`),
+			value: `provider::gotter::execute_map({ broken = "{{ index .tags 5 }}", fine = "{{ .name }}" }, { name = "test", tags = ["a"] })`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resource.UnitTest(t, resource.TestCase{
+				ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+					"gotter": providerserver.NewProtocol6WithError(New("dev")()),
+				},
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`output "test" { value = %s }`, test.value),
+						ConfigStateChecks: []statecheck.StateCheck{
+							statecheck.ExpectKnownOutputValue("test", test.check),
+						},
+						ExpectError: test.error,
+					},
+				},
+				TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+					tfversion.SkipBelow(tfversion.Version1_8_0),
+				},
+			})
+		})
+	}
+}
+
+func TestGotterProviderEach(t *testing.T) {
+	for name, test := range map[string]struct {
+		check knownvalue.Check
+		error *regexp.Regexp
+		value string
+	}{
+		"list": {
+			check: knownvalue.ListExact([]knownvalue.Check{
+				knownvalue.StringExact("0: web.example.com"),
+				knownvalue.StringExact("1: db.example.com"),
+			}),
+			value: `provider::gotter::execute_each("{{ .index }}: {{ .item }}.{{ .shared.domain }}", ["web", "db"], { domain = "example.com" })`,
+		},
+		"list_of_objects": {
+			check: knownvalue.ListExact([]knownvalue.Check{
+				knownvalue.StringExact("web:80"),
+				knownvalue.StringExact("db:5432"),
+			}),
+			value: `provider::gotter::execute_each("{{ .item.name }}:{{ .item.port }}", [{ name = "web", port = 80 }, { name = "db", port = 5432 }], null)`,
+		},
+		"set": {
+			check: knownvalue.ListExact([]knownvalue.Check{
+				knownvalue.StringExact("a"),
+				knownvalue.StringExact("b"),
+			}),
+			value: `provider::gotter::execute_each("{{ .item }}", toset(["b", "a"]), null)`,
+		},
+		"empty": {
+			check: knownvalue.ListExact([]knownvalue.Check{}),
+			value: `provider::gotter::execute_each("{{ .item }}", [], null)`,
+		},
+		"options": {
+			check: knownvalue.ListExact([]knownvalue.Check{
+				knownvalue.StringExact("WEB"),
+			}),
+			value: `provider::gotter::execute_each("<% .item | upper %>", ["web"], null, { left_delim = "<%", right_delim = "%>" })`,
+		},
+		"error": {
+			error: regexp.MustCompile(`element 1: .*index out of range`),
+			value: `provider::gotter::execute_each("{{ index .item 1 }}", [["a", "b"], ["c"]], null)`,
+		},
+		"not_a_list": {
+			error: regexp.MustCompile(`list must be a list, set or tuple`),
+			value: `provider::gotter::execute_each("{{ .item }}", "web", null)`,
+		},
+		"mustache": {
+			error: regexp.MustCompile(`option "engine" must be "go" for execute_each`),
+			value: `provider::gotter::execute_each("{{ item }}", ["web"], null, { engine = "mustache" })`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resource.UnitTest(t, resource.TestCase{
+				ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+					"gotter": providerserver.NewProtocol6WithError(New("dev")()),
+				},
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`output "test" { value = %s }`, test.value),
+						ConfigStateChecks: []statecheck.StateCheck{
+							statecheck.ExpectKnownOutputValue("test", test.check),
+						},
+						ExpectError: test.error,
+					},
+				},
+				TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+					tfversion.SkipBelow(tfversion.Version1_8_0),
+				},
+			})
+		})
+	}
+}
+
+func TestGotterProviderSections(t *testing.T) {
+	for name, test := range map[string]struct {
+		check knownvalue.Check
+		error *regexp.Regexp
+		value string
+	}{
+		"sections": {
+			check: knownvalue.MapExact(map[string]knownvalue.Check{
+				"timer":   knownvalue.StringExact("[Timer]\nOnCalendar=daily\n"),
+				"service": knownvalue.StringExact("[Service]\nExecStart=/usr/bin/backup\n"),
+			}),
+			value: `provider::gotter::execute_sections("{{ $name := .name }}{{ section \"service\" }}[Service]\nExecStart=/usr/bin/{{ $name }}\n{{ end }}{{ section \"timer\" }}[Timer]\nOnCalendar={{ .schedule }}\n{{ end }}", { name = "backup", schedule = "daily" })`,
+		},
+		"nested": {
+			check: knownvalue.MapExact(map[string]knownvalue.Check{
+				"inner": knownvalue.StringExact("b"),
+				"outer": knownvalue.StringExact("abc"),
+			}),
+			value: `provider::gotter::execute_sections("ignored{{ section \"outer\" }}a{{ section \"inner\" }}b{{ end }}c{{ end }}ignored", null)`,
+		},
+		"range": {
+			check: knownvalue.MapExact(map[string]knownvalue.Check{
+				"db":  knownvalue.StringExact("5432"),
+				"web": knownvalue.StringExact("80"),
+			}),
+			value: `provider::gotter::execute_sections("{{ range $k, $v := . }}{{ section $k }}{{ $v }}{{ end }}{{ end }}", { web = 80, db = 5432 })`,
+		},
+		"options": {
+			check: knownvalue.MapExact(map[string]knownvalue.Check{
+				"unit": knownvalue.StringExact("WEB\n"),
+			}),
+			value: `provider::gotter::execute_sections("<% section \"unit\" %>\n<% .name | upper %>\n<% end %>\n", { name = "web" }, { left_delim = "<%", right_delim = "%>", trim_blocks = true })`,
+		},
+		"duplicate": {
+			error: regexp.MustCompile(`section "unit" is declared more than once`),
+			value: `provider::gotter::execute_sections("{{ section \"unit\" }}a{{ end }}{{ section \"unit\" }}b{{ end }}", null)`,
+		},
+		"else": {
+			error: regexp.MustCompile(`section cannot have an else branch`),
+			value: `provider::gotter::execute_sections("{{ section \"unit\" }}a{{ else }}b{{ end }}", null)`,
+		},
+		"include": {
+			error: regexp.MustCompile(`section "unit" cannot be declared by a template executed by include`),
+			value: `provider::gotter::execute_sections("{{ define \"x\" }}{{ section \"unit\" }}a{{ end }}{{ end }}{{ include \"x\" . }}", null)`,
+		},
+		"mustache": {
+			error: regexp.MustCompile(`option "engine" must be "go" for execute_sections`),
+			value: `provider::gotter::execute_sections("{{ name }}", null, { engine = "mustache" })`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resource.UnitTest(t, resource.TestCase{
+				ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+					"gotter": providerserver.NewProtocol6WithError(New("dev")()),
+				},
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`output "test" { value = %s }`, test.value),
+						ConfigStateChecks: []statecheck.StateCheck{
+							statecheck.ExpectKnownOutputValue("test", test.check),
+						},
+						ExpectError: test.error,
+					},
+				},
+				TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+					tfversion.SkipBelow(tfversion.Version1_8_0),
+				},
+			})
+		})
+	}
+}
+
+func TestGotterProviderLayers(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "hello.tmpl")
+
+	if err := os.WriteFile(file, []byte(`Hello, {{ .name }}!`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, test := range map[string]struct {
+		check knownvalue.Check
+		error *regexp.Regexp
+		value string
+	}{
+		"deep": {
+			check: knownvalue.StringExact("prod db.prod:5432"),
+			value: `provider::gotter::execute("{{ .env }} {{ .db.host }}:{{ .db.port }}", { env = "dev", db = { host = "localhost", port = 5432 } }, { layers = [{ env = "prod" }, { db = { host = "db.prod" } }] })`,
+		},
+		"later_layers_win": {
+			check: knownvalue.StringExact("c"),
+			value: `provider::gotter::execute("{{ .name }}", { name = "a" }, { layers = [{ name = "b" }] }, { layers = [{ name = "c" }] })`,
+		},
+		"null": {
+			check: knownvalue.StringExact("a"),
+			value: `provider::gotter::execute("{{ .name }}", { name = "a" }, { layers = [{ name = null }] })`,
+		},
+		"null_without_object": {
+			check: knownvalue.StringExact("1"),
+			value: `provider::gotter::execute("{{ len .db }}", { db = "none" }, { layers = [{ db = { host = "db.prod", port = null } }] })`,
+		},
+		"replace": {
+			check: knownvalue.StringExact("c,"),
+			value: `provider::gotter::execute("{{ range .items }}{{ . }},{{ end }}", { items = ["a", "b"] }, { layers = [{ items = ["c"] }] })`,
+		},
+		"append": {
+			check: knownvalue.StringExact("a,b,c,"),
+			value: `provider::gotter::execute("{{ range .items }}{{ . }},{{ end }}", { items = ["a", "b"] }, { layers = [{ items = ["c"] }], list_merge = "append" })`,
+		},
+		"key": {
+			check: knownvalue.StringExact("web=8080,db=5432,cache=6379,"),
+			value: `provider::gotter::execute("{{ range .services }}{{ .name }}={{ .port }},{{ end }}", { services = [{ name = "web", port = 80 }, { name = "db", port = 5432 }] }, { layers = [{ services = [{ name = "web", port = 8080 }, { name = "cache", port = 6379 }] }], list_merge = "key", list_merge_key = "name" })`,
+		},
+		"file": {
+			check: knownvalue.StringExact("Hello, Layers!"),
+			value: fmt.Sprintf(`provider::gotter::execute_file(%q, { name = "World" }, { layers = [{ name = "Layers" }] })`, file),
+		},
+		"missing_key": {
+			error: regexp.MustCompile(`option "list_merge_key" must be set when option "list_merge" is "key"`),
+			value: `provider::gotter::execute("", null, { list_merge = "key" })`,
+		},
+		"missing_element_key": {
+			error: regexp.MustCompile(`layer 0: .services\[0\]: list elements must have a "id" key to be merged by key`),
+			value: `provider::gotter::execute("", { services = [{ name = "web" }] }, { layers = [{ services = [{ name = "db" }] }], list_merge = "key", list_merge_key = "id" })`,
+		},
+		"invalid_list_merge": {
+			error: regexp.MustCompile(`option "list_merge" must be one of append, key, replace, got "merge"`),
+			value: `provider::gotter::execute("", null, { list_merge = "merge" })`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resource.UnitTest(t, resource.TestCase{
+				ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+					"gotter": providerserver.NewProtocol6WithError(New("dev")()),
+				},
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`output "test" { value = %s }`, test.value),
+						ConfigStateChecks: []statecheck.StateCheck{
+							statecheck.ExpectKnownOutputValue("test", test.check),
+						},
+						ExpectError: test.error,
+					},
+				},
+				TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+					tfversion.SkipBelow(tfversion.Version1_8_0),
+				},
+			})
+		})
+	}
+}
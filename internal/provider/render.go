@@ -0,0 +1,328 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"go.austindrenski.io/gotter/templates"
+)
+
+// parsePartials parses each partial into t as an associated template named
+// after its key, so that the entry template can invoke it with template or
+// override one of its blocks. It reports the index of the first map holding a
+// partial that fails to parse or that declares a template already declared
+// by another partial.
+func parsePartials(ctx context.Context, t *template.Template, partials []map[string]string, opts ...func(context.Context, *template.Template) *template.Template) (int, error) {
+	opts = append([]func(context.Context, *template.Template) *template.Template{templates.WithFuncs(functions)}, opts...)
+
+	declared := map[string]string{}
+
+	for i, m := range partials {
+		for _, key := range slices.Sorted(maps.Keys(m)) {
+			if key == t.Name() {
+				return i, fmt.Errorf("partial %q conflicts with the entry template", key)
+			}
+
+			p, err := templates.Parse(ctx, key, m[key], opts...)
+			if err != nil {
+				return i, err
+			}
+
+			for _, d := range p.Templates() {
+				if other, ok := declared[d.Name()]; !ok {
+					declared[d.Name()] = key
+				} else if other == key {
+					return i, fmt.Errorf("partial %q is defined more than once", key)
+				} else {
+					return i, fmt.Errorf("partial %q redefines template %q, already defined by partial %q", key, d.Name(), other)
+				}
+			}
+
+			for _, d := range p.Templates() {
+				if _, err := t.AddParseTree(d.Name(), d.Tree); err != nil {
+					return i, err
+				}
+			}
+		}
+	}
+
+	return 0, nil
+}
+
+// lookup returns the template with the given name from the templates
+// associated with t.
+func lookup(t *template.Template, name string) (*template.Template, error) {
+	if entry := t.Lookup(name); entry != nil {
+		return entry, nil
+	}
+
+	var names []string
+	for _, t := range t.Templates() {
+		names = append(names, fmt.Sprintf("%q", t.Name()))
+	}
+
+	slices.Sort(names)
+
+	return nil, fmt.Errorf("template %q is not defined; expected one of %s", name, strings.Join(names, ", "))
+}
+
+// parseGlob parses every file matching the pattern into a single set of
+// associated templates, naming each after its path relative to the directory
+// preceding the first wildcard, e.g. "partials/header.tmpl" for the file
+// "templates/partials/header.tmpl" matched by "templates/*/*.tmpl".
+func parseGlob(ctx context.Context, pattern string) (*template.Template, error) {
+	files, err := glob(pattern)
+	if err != nil {
+		return nil, err
+	} else if len(files) == 0 {
+		return nil, fmt.Errorf("pattern %q matches no files", pattern)
+	}
+
+	base := globBase(pattern)
+
+	var names, texts []string
+
+	for _, file := range files {
+		if stat, err := os.Stat(file); err != nil {
+			return nil, err
+		} else if stat.IsDir() {
+			continue
+		}
+
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		name, err := filepath.Rel(base, file)
+		if err != nil {
+			return nil, err
+		}
+
+		names = append(names, filepath.ToSlash(name))
+		texts = append(texts, string(b))
+	}
+
+	var t *template.Template
+
+	// Every file is read before any is parsed, so that functions declared by
+	// one file can be called from all of them.
+	for i, name := range names {
+		if t == nil {
+			if t, err = templates.Parse(ctx, name, texts[i], templates.WithFuncs(functions), withDefinedFuncs(texts...)); err != nil {
+				return nil, err
+			}
+		} else if _, err := t.New(name).Parse(texts[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if t == nil {
+		return nil, fmt.Errorf("pattern %q matches no files", pattern)
+	}
+
+	return t, nil
+}
+
+// glob extends filepath.Glob with support for "**", which matches zero or more
+// directories.
+func glob(pattern string) ([]string, error) {
+	if !strings.Contains(pattern, "**") {
+		return filepath.Glob(pattern)
+	}
+
+	base := globBase(pattern)
+
+	rel, err := filepath.Rel(base, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+
+	for _, segment := range segments {
+		if _, err := filepath.Match(segment, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	var files []string
+
+	err = filepath.WalkDir(base, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		name, err := filepath.Rel(base, file)
+		if err != nil {
+			return err
+		}
+
+		if globMatch(segments, strings.Split(filepath.ToSlash(name), "/")) {
+			files = append(files, file)
+		}
+
+		return nil
+	})
+
+	return files, err
+}
+
+func globMatch(pattern []string, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := range len(name) + 1 {
+				if globMatch(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		} else if ok, _ := filepath.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// globBase returns the directory preceding the first wildcard in the pattern.
+func globBase(pattern string) string {
+	dir := filepath.Dir(pattern)
+
+	for strings.ContainsAny(dir, "*?[") {
+		dir = filepath.Dir(dir)
+	}
+
+	return dir
+}
+
+// render executes the template with the unwrapped data, reporting false if
+// the template read a value that is not known until apply.
+func render(ctx context.Context, t *template.Template, data attr.Value) (string, bool, error) {
+	return renderUnwrapped(ctx, t, unwrap(data))
+}
+
+// renderUnwrapped executes the template with data that has already been
+// unwrapped, so that data shared by several templates is only unwrapped once,
+// see render.
+func renderUnwrapped(ctx context.Context, t *template.Template, v any) (string, bool, error) {
+	var g guarder
+
+	if !known(v) {
+		g = guard(t)
+	}
+
+	return renderGuarded(ctx, t, v, g)
+}
+
+// renderGuarded executes the template with unwrapped data, where g is the
+// result of guard if it has already been applied to the template, or the zero
+// guarder otherwise, so that a template executed with many values is only
+// guarded once.
+func renderGuarded(ctx context.Context, t *template.Template, v any, g guarder) (string, bool, error) {
+	compose(t, g, new(int))
+
+	b := strings.Builder{}
+	if err := templates.Execute(ctx, t, v, &b); errors.Is(err, errUnknown) {
+		return "", false, nil
+	} else if err != nil {
+		return "", true, g.restore(err)
+	}
+
+	return b.String(), true, nil
+}
+
+// stat reports whether the file exists and can be parsed as a template.
+func stat(file string) error {
+	if stat, err := os.Stat(file); err != nil {
+		return err
+	} else if stat.IsDir() {
+		return fmt.Errorf("%q is a directory", file)
+	} else if stat.Size() == 0 {
+		return fmt.Errorf("%q is empty", file)
+	} else {
+		return nil
+	}
+}
+
+// renderSource renders the template from whichever of the text or file
+// attributes is set with the data and options attributes, in the same way as
+// execute and execute_file, reporting false if the options or any value that
+// the template read are not yet known. Errors are attributed to the template
+// or options attributes where they can be.
+func renderSource(ctx context.Context, text types.String, file types.String, data types.Dynamic, options types.Dynamic) (string, bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	source, s := path.Root("text"), text.ValueString()
+
+	if !file.IsNull() {
+		source, s = path.Root("file"), file.ValueString()
+
+		if err := stat(s); err != nil {
+			diags.AddAttributeError(source, "Invalid template file", err.Error())
+			return "", false, diags
+		}
+	}
+
+	var values []types.Dynamic
+	if !options.IsNull() {
+		values = append(values, options)
+	}
+
+	// The arguments of execute and execute_file are the template, the data
+	// and then the options.
+	s, ok, ferr := execute{file: !file.IsNull()}.run(ctx, s, data, values)
+	if ferr != nil && ferr.FunctionArgument == nil {
+		diags.AddError("Failed to execute template", ferr.Text)
+	} else if ferr != nil && *ferr.FunctionArgument == 0 {
+		diags.AddAttributeError(source, "Invalid template", ferr.Text)
+	} else if ferr != nil {
+		diags.AddAttributeError(path.Root("options"), "Invalid options", ferr.Text)
+	}
+
+	return s, ok, diags
+}
+
+// validateSource reports an error unless exactly one of the text or file
+// attributes is set.
+func validateSource(text types.String, file types.String) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if text.IsUnknown() || file.IsUnknown() {
+		return diags
+	}
+
+	if text.IsNull() == file.IsNull() {
+		diags.AddAttributeError(path.Root("text"), "Invalid attribute combination", "Exactly one of `text` or `file` must be specified")
+	}
+
+	return diags
+}
+
+// checksums returns the hex-encoded and base64-encoded SHA256 checksums.
+func checksums(b []byte) (string, string) {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), base64.StdEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,181 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"unicode"
+)
+
+// sectioners holds placeholders for the functions that a section recorder
+// binds to a template, so that templates using them can be parsed before
+// they are bound.
+var sectioners = template.FuncMap{
+	"_end_section": func() (string, error) {
+		return "", errors.New("section is only available in execute_sections")
+	},
+	"section": func(name string) (bool, error) {
+		return false, fmt.Errorf("section %q is only available in execute_sections", name)
+	},
+}
+
+// rewriteSections rewrites each {{ section "name" }} action in the template
+// text into {{ if section "name" }}, so that the parser accepts the {{ end }}
+// that closes it. Only the columns of the text that follows are changed.
+func rewriteSections(text string, left string, right string) string {
+	var b strings.Builder
+	var pos int
+
+	for _, tag := range scanTags(text, left, right) {
+		p := tag.start + len(left)
+		if tag.leftTrim {
+			p += 2
+		}
+
+		p += len(text[p:]) - len(strings.TrimLeft(text[p:], " \t\r\n"))
+
+		if rest, ok := strings.CutPrefix(text[p:tag.end], "section"); ok && rest != "" && unicode.IsSpace(rune(rest[0])) {
+			b.WriteString(text[pos:p])
+			b.WriteString("if ")
+			pos = p
+		}
+	}
+
+	b.WriteString(text[pos:])
+
+	return b.String()
+}
+
+// closeSections appends an action that ends the section to the body of each
+// {{ if section "name" }} block in t and its associated templates, so that
+// the recorder knows where each section ends.
+func closeSections(t *template.Template) error {
+	seen := map[*parse.Tree]bool{}
+
+	for _, t := range t.Templates() {
+		if t.Tree == nil || t.Tree.Root == nil || seen[t.Tree] {
+			continue
+		}
+
+		seen[t.Tree] = true
+
+		if err := closeSectionNode(t.Tree, t.Tree.Root); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func closeSectionNode(tree *parse.Tree, node parse.Node) error {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		for _, n := range node.Nodes {
+			if err := closeSectionNode(tree, n); err != nil {
+				return err
+			}
+		}
+	case *parse.IfNode:
+		if cmds := node.Pipe.Cmds; len(node.Pipe.Decl) == 0 && len(cmds) > 0 && len(cmds[0].Args) > 0 {
+			if ident, ok := cmds[0].Args[0].(*parse.IdentifierNode); ok && ident.Ident == "section" {
+				if node.ElseList != nil {
+					location, _ := tree.ErrorContext(node)
+					return fmt.Errorf("template: %s: section cannot have an else branch", location)
+				}
+
+				if node.List == nil {
+					node.List = &parse.ListNode{NodeType: parse.NodeList, Pos: node.Pos}
+				}
+
+				node.List.Nodes = append(node.List.Nodes, &parse.ActionNode{
+					NodeType: parse.NodeAction,
+					Pos:      node.Pos,
+					Line:     node.Line,
+					Pipe: &parse.PipeNode{
+						NodeType: parse.NodePipe,
+						Pos:      node.Pos,
+						Line:     node.Line,
+						Cmds:     []*parse.CommandNode{command(node.Pos, identifier(node.Pos, "_end_section"))},
+					},
+				})
+			}
+		}
+
+		return closeSectionBranch(tree, &node.BranchNode)
+	case *parse.RangeNode:
+		return closeSectionBranch(tree, &node.BranchNode)
+	case *parse.WithNode:
+		return closeSectionBranch(tree, &node.BranchNode)
+	}
+
+	return nil
+}
+
+func closeSectionBranch(tree *parse.Tree, node *parse.BranchNode) error {
+	if node.List != nil {
+		if err := closeSectionNode(tree, node.List); err != nil {
+			return err
+		}
+	}
+
+	if node.ElseList != nil {
+		return closeSectionNode(tree, node.ElseList)
+	}
+
+	return nil
+}
+
+// sectionRecorder captures the text of each section as the template writes
+// it, by noting where in the output each section starts and ends. Sections
+// therefore see the variables of the enclosing template, and sections may be
+// nested. The depth is the depth passed to compose.
+type sectionRecorder struct {
+	b     strings.Builder
+	depth *int
+	open  []openSection
+	texts map[string]string
+}
+
+// openSection is a section whose end has not yet been written.
+type openSection struct {
+	name  string
+	start int
+}
+
+// funcs returns the section functions bound to the recorder. Sections cannot
+// be declared by templates that include, tpl or template functions execute,
+// since their output is not written to the recorder until they return.
+func (r *sectionRecorder) funcs() template.FuncMap {
+	return template.FuncMap{
+		"_end_section": func() string {
+			s := r.open[len(r.open)-1]
+			r.open = r.open[:len(r.open)-1]
+			r.texts[s.name] = r.b.String()[s.start:]
+
+			return ""
+		},
+		"section": func(name string) (bool, error) {
+			if *r.depth > 0 {
+				return false, fmt.Errorf("section %q cannot be declared by a template executed by include, tpl or a template function", name)
+			} else if name == "" {
+				return false, errors.New("section name must not be empty")
+			}
+
+			if _, ok := r.texts[name]; ok {
+				return false, fmt.Errorf("section %q is declared more than once", name)
+			}
+
+			for _, s := range r.open {
+				if s.name == name {
+					return false, fmt.Errorf("section %q is declared more than once", name)
+				}
+			}
+
+			r.open = append(r.open, openSection{name: name, start: r.b.Len()})
+
+			return true, nil
+		},
+	}
+}
@@ -0,0 +1,100 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ datasource.DataSource                   = (*templateDataSource)(nil)
+	_ datasource.DataSourceWithValidateConfig = (*templateDataSource)(nil)
+)
+
+type templateDataSource struct{}
+
+type templateDataSourceModel struct {
+	Base64Sha256 types.String  `tfsdk:"base64sha256"`
+	Data         types.Dynamic `tfsdk:"data"`
+	File         types.String  `tfsdk:"file"`
+	Options      types.Dynamic `tfsdk:"options"`
+	Rendered     types.String  `tfsdk:"rendered"`
+	Sha256       types.String  `tfsdk:"sha256"`
+	Text         types.String  `tfsdk:"text"`
+}
+
+func (d templateDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_template"
+}
+
+func (d templateDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var model templateDataSourceModel
+
+	if resp.Diagnostics.Append(req.Config.Get(ctx, &model)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	s, ok, diags := renderSource(ctx, model.Text, model.File, model.Data, model.Options)
+	if resp.Diagnostics.Append(diags...); resp.Diagnostics.HasError() {
+		return
+	} else if !ok {
+		resp.Diagnostics.AddError("Failed to execute template", "The template read a value that is not known until apply")
+		return
+	}
+
+	sha256, base64sha256 := checksums([]byte(s))
+
+	model.Base64Sha256 = types.StringValue(base64sha256)
+	model.Rendered = types.StringValue(s)
+	model.Sha256 = types.StringValue(sha256)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
+}
+
+func (d templateDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"base64sha256": schema.StringAttribute{
+				Computed:    true,
+				Description: "The base64-encoded SHA256 checksum of the rendered template",
+			},
+			"data": schema.DynamicAttribute{
+				Description: "The data passed to the template",
+				Optional:    true,
+			},
+			"file": schema.StringAttribute{
+				Description: "The text template file. Conflicts with `text`.",
+				Optional:    true,
+			},
+			"options": schema.DynamicAttribute{
+				Description: "An object configuring the template, with the same keys as the options of the `execute` function",
+				Optional:    true,
+			},
+			"rendered": schema.StringAttribute{
+				Computed:    true,
+				Description: "The rendered template",
+			},
+			"sha256": schema.StringAttribute{
+				Computed:    true,
+				Description: "The hex-encoded SHA256 checksum of the rendered template",
+			},
+			"text": schema.StringAttribute{
+				Description: "The text template. Conflicts with `file`.",
+				Optional:    true,
+			},
+		},
+		Description: "Executes a Go text/template from `text` or `file` using the provided `data` and `options`",
+	}
+}
+
+func (d templateDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var model templateDataSourceModel
+
+	if resp.Diagnostics.Append(req.Config.Get(ctx, &model)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(validateSource(model.Text, model.File)...)
+}
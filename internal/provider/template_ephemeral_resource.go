@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ ephemeral.EphemeralResource                   = (*templateEphemeralResource)(nil)
+	_ ephemeral.EphemeralResourceWithValidateConfig = (*templateEphemeralResource)(nil)
+)
+
+type templateEphemeralResource struct{}
+
+type templateEphemeralResourceModel struct {
+	Data     types.Dynamic `tfsdk:"data"`
+	File     types.String  `tfsdk:"file"`
+	Options  types.Dynamic `tfsdk:"options"`
+	Rendered types.String  `tfsdk:"rendered"`
+	Text     types.String  `tfsdk:"text"`
+}
+
+func (e templateEphemeralResource) Metadata(_ context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_template"
+}
+
+func (e templateEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	var model templateEphemeralResourceModel
+
+	if resp.Diagnostics.Append(req.Config.Get(ctx, &model)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	s, ok, diags := renderSource(ctx, model.Text, model.File, model.Data, model.Options)
+	if resp.Diagnostics.Append(diags...); resp.Diagnostics.HasError() {
+		return
+	} else if !ok {
+		model.Rendered = types.StringUnknown()
+	} else {
+		model.Rendered = types.StringValue(s)
+	}
+
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &model)...)
+}
+
+func (e templateEphemeralResource) Schema(_ context.Context, _ ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"data": schema.DynamicAttribute{
+				Description: "The data passed to the template",
+				Optional:    true,
+			},
+			"file": schema.StringAttribute{
+				Description: "The text template file. Conflicts with `text`.",
+				Optional:    true,
+			},
+			"options": schema.DynamicAttribute{
+				Description: "An object configuring the template, with the same keys as the options of the `execute` function",
+				Optional:    true,
+			},
+			"rendered": schema.StringAttribute{
+				Computed:    true,
+				Description: "The rendered template",
+				Sensitive:   true,
+			},
+			"text": schema.StringAttribute{
+				Description: "The text template. Conflicts with `file`.",
+				Optional:    true,
+			},
+		},
+		Description: "Executes a Go text/template from `text` or `file` using the provided `data` and `options` without persisting the result to the plan or state",
+	}
+}
+
+func (e templateEphemeralResource) ValidateConfig(ctx context.Context, req ephemeral.ValidateConfigRequest, resp *ephemeral.ValidateConfigResponse) {
+	var model templateEphemeralResourceModel
+
+	if resp.Diagnostics.Append(req.Config.Get(ctx, &model)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(validateSource(model.Text, model.File)...)
+}
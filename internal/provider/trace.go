@@ -0,0 +1,222 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"go.austindrenski.io/gotter/templates"
+)
+
+// tracer records where in the rendered output the text and actions of a set
+// of templates begin, so that a position in the output can be traced back to
+// the template that wrote it. Output written by include, tpl and template
+// functions is attributed to the action that called them, since their
+// templates are executed into a separate buffer. The depth is the depth
+// passed to compose.
+type tracer struct {
+	b       strings.Builder
+	depth   *int
+	marks   []traceMark
+	nodes   []tracedNode
+	sources map[string]templateSource
+}
+
+// traceMark records the offset in the output at which a node began writing.
+type traceMark struct {
+	node   int
+	offset int
+}
+
+// tracedNode is a node of a parse tree that writes to the output.
+type tracedNode struct {
+	node parse.Node
+	tree *parse.Tree
+}
+
+// templateSource is the text of a template as written and as parsed, which
+// differ when trim_blocks or lstrip_blocks is set, and a description of the
+// template for use in errors.
+type templateSource struct {
+	name   string
+	parsed string
+	text   string
+}
+
+// trace renders a Go template like template, but records the output of each
+// of its text and actions, see tracer.
+func (f execute) trace(ctx context.Context, text string, data any, opts executeOptions, entry int, parent int) (*tracer, bool, *function.FuncError) {
+	t, ferr := f.parse(ctx, text, opts, entry, parent)
+	if ferr != nil {
+		return nil, false, ferr
+	}
+
+	r := tracer{depth: new(int), sources: f.sources(text, opts)}
+
+	var g guarder
+
+	if !known(data) {
+		g = guard(t)
+	}
+
+	r.instrument(t)
+
+	compose(t, g, r.depth)
+	t.Funcs(r.funcs())
+
+	if err := templates.Execute(ctx, t, data, &r.b); errors.Is(err, errUnknown) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, function.NewFuncError(g.restore(err).Error())
+	}
+
+	return &r, true, nil
+}
+
+// sources returns the text of the entry template, its layouts and its
+// partials, keyed by the name under which each was parsed.
+func (f execute) sources(text string, opts executeOptions) map[string]templateSource {
+	sources := map[string]templateSource{}
+
+	// Errors were already reported by parse.
+	chain, _ := f.layouts(text, opts)
+
+	for _, l := range chain {
+		sources[l.name] = templateSource{name: l.String(), parsed: opts.prepare(l.text), text: l.text}
+	}
+
+	for _, m := range opts.partials {
+		for k, v := range m {
+			sources[k] = templateSource{name: fmt.Sprintf("partial %q", k), parsed: opts.prepare(v), text: v}
+		}
+	}
+
+	return sources
+}
+
+// instrument inserts an action before each text, action and template node of
+// t and its associated templates that records where the node began writing.
+func (r *tracer) instrument(t *template.Template) {
+	seen := map[*parse.Tree]bool{}
+
+	for _, t := range t.Templates() {
+		if t.Tree == nil || t.Tree.Root == nil || seen[t.Tree] {
+			continue
+		}
+
+		seen[t.Tree] = true
+
+		r.instrumentNode(t.Tree, t.Tree.Root)
+	}
+}
+
+func (r *tracer) instrumentNode(tree *parse.Tree, node parse.Node) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		nodes := make([]parse.Node, 0, 2*len(node.Nodes))
+
+		for _, n := range node.Nodes {
+			switch n.(type) {
+			case *parse.ActionNode, *parse.TemplateNode, *parse.TextNode:
+				nodes = append(nodes, r.mark(tree, n))
+			default:
+				r.instrumentNode(tree, n)
+			}
+
+			nodes = append(nodes, n)
+		}
+
+		node.Nodes = nodes
+	case *parse.IfNode:
+		r.instrumentBranch(tree, &node.BranchNode)
+	case *parse.RangeNode:
+		r.instrumentBranch(tree, &node.BranchNode)
+	case *parse.WithNode:
+		r.instrumentBranch(tree, &node.BranchNode)
+	}
+}
+
+func (r *tracer) instrumentBranch(tree *parse.Tree, node *parse.BranchNode) {
+	if node.List != nil {
+		r.instrumentNode(tree, node.List)
+	}
+
+	if node.ElseList != nil {
+		r.instrumentNode(tree, node.ElseList)
+	}
+}
+
+// mark returns an action that records where the node begins writing.
+func (r *tracer) mark(tree *parse.Tree, node parse.Node) *parse.ActionNode {
+	id := len(r.nodes)
+	pos := node.Position()
+
+	r.nodes = append(r.nodes, tracedNode{node: node, tree: tree})
+
+	return &parse.ActionNode{
+		NodeType: parse.NodeAction,
+		Pos:      pos,
+		Pipe: &parse.PipeNode{
+			NodeType: parse.NodePipe,
+			Pos:      pos,
+			Cmds: []*parse.CommandNode{command(pos, identifier(pos, "_trace"), &parse.NumberNode{
+				NodeType: parse.NodeNumber,
+				Pos:      pos,
+				IsInt:    true,
+				Int64:    int64(id),
+				Text:     strconv.Itoa(id),
+			})},
+		},
+	}
+}
+
+// funcs returns the function that the actions inserted by instrument call.
+func (r *tracer) funcs() template.FuncMap {
+	return template.FuncMap{
+		"_trace": func(id int) string {
+			if *r.depth == 0 {
+				r.marks = append(r.marks, traceMark{node: id, offset: r.b.Len()})
+			}
+
+			return ""
+		},
+	}
+}
+
+// locate returns the position in its template of the text or action that
+// wrote the byte at the offset of the output, reporting false if it is not
+// known.
+func (r *tracer) locate(offset int) (*templateLocation, bool) {
+	// The last node to begin writing at or before the offset wrote it, since
+	// any others that began at the same offset wrote nothing.
+	i := sort.Search(len(r.marks), func(i int) bool {
+		return r.marks[i].offset > offset
+	}) - 1
+
+	if i < 0 {
+		return nil, false
+	}
+
+	traced := r.nodes[r.marks[i].node]
+
+	source, ok := r.sources[traced.tree.ParseName]
+	if !ok {
+		return nil, false
+	}
+
+	pos := int(traced.node.Position())
+
+	if text, ok := traced.node.(*parse.TextNode); ok && offset-r.marks[i].offset < len(text.Text) {
+		pos += offset - r.marks[i].offset
+	}
+
+	line, column := position(source.parsed, pos)
+
+	return &templateLocation{column: column, line: line, name: source.name, text: source.text}, true
+}
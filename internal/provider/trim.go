@@ -0,0 +1,178 @@
+package provider
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// blockKeywords lists the keywords of actions that open, continue or close a
+// block, or that otherwise produce no output of their own.
+var blockKeywords = []string{"block", "break", "continue", "define", "else", "end", "if", "range", "with"}
+
+// declaration matches actions that only declare or assign variables.
+var declaration = regexp.MustCompile(`^\$\w*\s*(,\s*\$\w*\s*)?:?=`)
+
+// trimTag is an action in the template text, where leftTrim and rightTrim
+// report whether it carries the `{{- ` and ` -}}` trim markers.
+type trimTag struct {
+	block     bool
+	end       int
+	leftTrim  bool
+	rightTrim bool
+	start     int
+}
+
+// trimBlocks removes the indentation before, and the newline after, each line
+// of the template text that holds nothing but block actions, comments and
+// whitespace, in the style of the Jinja trim_blocks and lstrip_blocks
+// options. Lines holding any other action or text are left untouched, as are
+// the edges of actions that already carry trim markers.
+//
+// Removed newlines are wrapped in comments rather than deleted, so that
+// errors still report the line numbers of the original text.
+func trimBlocks(text string, left string, right string, trim bool, lstrip bool) string {
+	tags := scanTags(text, left, right)
+
+	var b strings.Builder
+	var pos int
+
+	for lineStart, k := 0, 0; lineStart <= len(text) && k < len(tags); {
+		i := lineStart
+		standalone := true
+		first := k
+
+		var lineEnd int
+
+		for {
+			lineEnd = strings.IndexByte(text[i:], '\n')
+			if lineEnd < 0 {
+				lineEnd = len(text)
+			} else {
+				lineEnd += i
+			}
+
+			if k < len(tags) && tags[k].start < lineEnd {
+				standalone = standalone && tags[k].block && whitespace(text[i:tags[k].start])
+				i = tags[k].end
+				k++
+			} else {
+				standalone = standalone && whitespace(strings.TrimSuffix(text[i:lineEnd], "\r"))
+				break
+			}
+		}
+
+		if standalone && k > first {
+			if lstrip && !tags[first].leftTrim {
+				b.WriteString(text[pos:lineStart])
+				pos = tags[first].start
+			}
+
+			if last := tags[k-1]; trim && !last.rightTrim && lineEnd < len(text) {
+				b.WriteString(text[pos:last.end])
+				b.WriteString(left + "/*" + text[last.end:lineEnd+1] + "*/" + right)
+				pos = lineEnd + 1
+			}
+		}
+
+		lineStart = lineEnd + 1
+	}
+
+	b.WriteString(text[pos:])
+
+	return b.String()
+}
+
+// scanTags finds the actions in the template text in the same way as the
+// text/template lexer. Scanning stops at the first malformed action, which is
+// left for the parser to report.
+func scanTags(text string, left string, right string) []trimTag {
+	var tags []trimTag
+
+	for pos := 0; pos < len(text); {
+		i := strings.Index(text[pos:], left)
+		if i < 0 {
+			break
+		}
+
+		tag := trimTag{start: pos + i}
+		p := tag.start + len(left)
+
+		if len(text) > p+1 && text[p] == '-' && trimSpace(text[p+1]) {
+			tag.leftTrim = true
+			p += 2
+		}
+
+		if strings.HasPrefix(text[p:], "/*") {
+			j := strings.Index(text[p:], "*/")
+			if j < 0 {
+				break
+			}
+
+			p += j + 2
+			tag.block = true
+		} else {
+			content := strings.TrimLeft(text[p:], " \t\r\n")
+			word := content[:len(content)-len(strings.TrimLeftFunc(content, isIdentifier))]
+
+			tag.block = slices.Contains(blockKeywords, word) || declaration.MatchString(content)
+		}
+
+		end, rightTrim, ok := scanAction(text, p, right)
+		if !ok {
+			break
+		}
+
+		tag.end = end
+		tag.rightTrim = rightTrim
+		tags = append(tags, tag)
+		pos = end
+	}
+
+	return tags
+}
+
+// scanAction returns the position after the right delimiter that closes the
+// action starting at pos, skipping over quoted strings and characters.
+func scanAction(text string, pos int, right string) (int, bool, bool) {
+	for pos < len(text) {
+		if strings.HasPrefix(text[pos:], right) {
+			return pos + len(right), false, true
+		} else if trimSpace(text[pos]) && strings.HasPrefix(text[pos+1:], "-"+right) {
+			return pos + 1 + len("-"+right), true, true
+		}
+
+		switch quote := text[pos]; quote {
+		case '"', '\'', '`':
+			pos++
+
+			for pos < len(text) && text[pos] != quote {
+				if text[pos] == '\\' && quote != '`' {
+					pos++
+				} else if text[pos] == '\n' && quote != '`' {
+					return 0, false, false
+				}
+
+				pos++
+			}
+
+			if pos >= len(text) {
+				return 0, false, false
+			}
+		}
+
+		pos++
+	}
+
+	return 0, false, false
+}
+
+// trimSpace reports whether the byte is one of the spaces that the
+// text/template lexer accepts next to a trim marker.
+func trimSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+func isIdentifier(r rune) bool {
+	return r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9'
+}
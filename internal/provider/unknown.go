@@ -0,0 +1,329 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// errUnknown aborts template execution when the template reads a value that
+// is not known until apply.
+var errUnknown = errors.New("value is not known until apply")
+
+// unknown stands in for a Terraform value that is not yet known.
+type unknown struct{}
+
+func (unknown) MarshalJSON() ([]byte, error) {
+	return nil, errUnknown
+}
+
+func (unknown) String() string {
+	return "(known after apply)"
+}
+
+// known reports whether the unwrapped value is free of unknown values at
+// every depth.
+func known(v any) bool {
+	switch v := v.(type) {
+	case unknown:
+		return false
+	case []any:
+		for _, v := range v {
+			if !known(v) {
+				return false
+			}
+		}
+	case map[string]any:
+		for _, v := range v {
+			if !known(v) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// guard rewrites the parse trees of t and its associated templates so that
+// execution fails with errUnknown as soon as an unknown value is read, while
+// templates that never touch an unknown value render as usual.
+//
+// Every data access, e.g. .a.b, is split into its individual steps, each of
+// which is checked before the next field is evaluated. Printed values are
+// additionally checked at every depth. Values assigned to variables are not
+// checked, since every use of a variable is.
+func guard(t *template.Template) guarder {
+	g := guarder{texts: map[parse.Node]guardedText{}}
+
+	t.Funcs(guards)
+
+	for _, t := range t.Templates() {
+		g.guardTree(t.Tree)
+	}
+
+	return g
+}
+
+// guards holds the functions that guarder inserts into parse trees.
+var guards = template.FuncMap{
+	"_known": func(v any) (reflect.Value, error) {
+		if _, ok := v.(unknown); ok {
+			return reflect.Value{}, errUnknown
+		} else if v == nil {
+			return reflect.Value{}, nil
+		} else {
+			return reflect.ValueOf(v), nil
+		}
+	},
+	"_known_all": func(v any) (reflect.Value, error) {
+		if !known(v) {
+			return reflect.Value{}, errUnknown
+		} else if v == nil {
+			return reflect.Value{}, nil
+		} else {
+			return reflect.ValueOf(v), nil
+		}
+	},
+}
+
+// guarder rewrites parse trees, see guard, recording the text as written of
+// each node that it inserts or rewrites, so that execution errors can be
+// reported against the template as written. The zero value rewrites nothing.
+type guarder struct {
+	// access is the text as written of the data access being rewritten.
+	access string
+	texts  map[parse.Node]guardedText
+	// tree is the parse tree being rewritten.
+	tree *parse.Tree
+}
+
+// guardedText is the text as written of a node in a parse tree.
+type guardedText struct {
+	text string
+	tree *parse.Tree
+}
+
+// guarded reports whether the guarder rewrites parse trees.
+func (g guarder) guarded() bool {
+	return g.texts != nil
+}
+
+// restore replaces the nodes that the guarder inserted or rewrote with their
+// text as written in the context of an execution error. Where nodes at the
+// same position read the same, the longest text is used.
+func (g guarder) restore(err error) error {
+	if !g.guarded() {
+		return err
+	}
+
+	texts := map[string]string{}
+
+	for node, t := range g.texts {
+		location, context := t.tree.ErrorContext(node)
+		if context == t.text {
+			continue
+		}
+
+		k := fmt.Sprintf("%s: executing %q at <%s>: ", location, t.tree.Name, context)
+		v := fmt.Sprintf("%s: executing %q at <%s>: ", location, t.tree.Name, t.text)
+
+		if len(v) > len(texts[k]) || len(v) == len(texts[k]) && v > texts[k] {
+			texts[k] = v
+		}
+	}
+
+	var pairs []string
+	for _, k := range slices.Sorted(maps.Keys(texts)) {
+		pairs = append(pairs, k, texts[k])
+	}
+
+	if s := strings.NewReplacer(pairs...).Replace(err.Error()); s != err.Error() {
+		return errors.New(s)
+	}
+
+	return err
+}
+
+// record records the text as written of the node.
+func (g guarder) record(node parse.Node, text string) {
+	g.texts[node] = guardedText{text: text, tree: g.tree}
+}
+
+// guardTree rewrites a single parse tree, see guard.
+func (g guarder) guardTree(tree *parse.Tree) {
+	if g.guarded() && tree != nil && tree.Root != nil {
+		g.tree = tree
+		g.node(tree.Root)
+	}
+}
+
+func (g guarder) node(node parse.Node) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		for _, n := range node.Nodes {
+			g.node(n)
+		}
+	case *parse.ActionNode:
+		// A variable may be assigned a value that is not yet known, since
+		// every use of the variable is checked itself.
+		if pipe := node.Pipe; len(pipe.Decl) > 0 && len(pipe.Cmds) == 1 && len(pipe.Cmds[0].Args) == 1 {
+			g.record(pipe, pipe.String())
+			g.record(pipe.Cmds[0], pipe.Cmds[0].String())
+
+			pipe.Cmds[0].Args[0] = g.arg(pipe.Cmds[0].Args[0], "")
+		} else {
+			g.pipe(pipe, "_known_all")
+		}
+	case *parse.IfNode:
+		g.branch(&node.BranchNode)
+	case *parse.RangeNode:
+		g.branch(&node.BranchNode)
+	case *parse.WithNode:
+		g.branch(&node.BranchNode)
+	case *parse.TemplateNode:
+		if node.Pipe != nil {
+			g.pipe(node.Pipe, "")
+		}
+	}
+}
+
+func (g guarder) branch(node *parse.BranchNode) {
+	g.pipe(node.Pipe, "_known")
+
+	if node.List != nil {
+		g.node(node.List)
+	}
+
+	if node.ElseList != nil {
+		g.node(node.ElseList)
+	}
+}
+
+// pipe guards the arguments of each command and, unless the pipeline declares
+// variables, appends a final call to check.
+//
+// Arguments passed to functions are checked at every depth, except for the
+// builtins that only inspect the structure of their arguments.
+func (g guarder) pipe(pipe *parse.PipeNode, check string) {
+	text := pipe.String()
+
+	g.record(pipe, text)
+
+	for _, cmd := range pipe.Cmds {
+		g.record(cmd, cmd.String())
+
+		deep := "_known_all"
+
+		if ident, ok := cmd.Args[0].(*parse.IdentifierNode); !ok {
+			deep = "_known"
+		} else if _, ok := structural[ident.Ident]; ok {
+			deep = "_known"
+		}
+
+		for i, arg := range cmd.Args {
+			if i == 0 && len(cmd.Args) > 1 {
+				continue
+			}
+
+			cmd.Args[i] = g.arg(arg, deep)
+		}
+	}
+
+	if check != "" && len(pipe.Decl) == 0 {
+		ident := identifier(pipe.Pos, check)
+
+		g.record(ident, text)
+
+		pipe.Cmds = append(pipe.Cmds, command(pipe.Pos, ident))
+	}
+}
+
+// structural lists the builtins that do not look inside their arguments, and
+// include and tpl, which pass their data to templates that are guarded
+// themselves.
+var structural = map[string]struct{}{
+	"and":     {},
+	"include": {},
+	"index":   {},
+	"len":     {},
+	"not":     {},
+	"or":      {},
+	"slice":   {},
+	"tpl":     {},
+}
+
+// arg checks every step of a data access, using check for the final value
+// unless it is empty.
+func (g guarder) arg(arg parse.Node, check string) parse.Node {
+	g.access = arg.String()
+
+	switch arg := arg.(type) {
+	case *parse.DotNode:
+		return g.checked(arg.Pos, arg, ".", check)
+	case *parse.FieldNode:
+		return g.chain(arg.Pos, &parse.DotNode{NodeType: parse.NodeDot, Pos: arg.Pos}, ".", arg.Ident, check)
+	case *parse.VariableNode:
+		return g.chain(arg.Pos, &parse.VariableNode{NodeType: parse.NodeVariable, Pos: arg.Pos, Ident: arg.Ident[:1]}, arg.Ident[0], arg.Ident[1:], check)
+	case *parse.ChainNode:
+		text := arg.Node.String()
+		if _, ok := arg.Node.(*parse.PipeNode); ok {
+			text = "(" + text + ")"
+		}
+
+		return g.chain(arg.Pos, g.arg(arg.Node, "_known"), text, arg.Field, check)
+	case *parse.PipeNode:
+		g.pipe(arg, check)
+		return arg
+	default:
+		return arg
+	}
+}
+
+// chain evaluates each field in turn, checking every intermediate value. The
+// text is the text as written of the node.
+func (g guarder) chain(pos parse.Pos, node parse.Node, text string, fields []string, check string) parse.Node {
+	for _, field := range fields {
+		node = g.checked(pos, node, text, "_known")
+		node = &parse.ChainNode{NodeType: parse.NodeChain, Pos: pos, Node: node, Field: []string{field}}
+
+		// A field of dot is written .a rather than ..a.
+		text = strings.TrimSuffix(text, ".") + "." + field
+		g.record(node, text)
+	}
+
+	return g.checked(pos, node, text, check)
+}
+
+// checked wraps the node in the pipeline (node | check), unless check is
+// empty. Errors in evaluating the rest of the data access are reported at the
+// call to check.
+func (g guarder) checked(pos parse.Pos, node parse.Node, text string, check string) parse.Node {
+	if check == "" {
+		return node
+	}
+
+	ident := identifier(pos, check)
+	pipe := &parse.PipeNode{
+		NodeType: parse.NodePipe,
+		Pos:      pos,
+		Cmds:     []*parse.CommandNode{command(pos, node), command(pos, ident)},
+	}
+
+	g.record(ident, g.access)
+	g.record(pipe, text)
+
+	return pipe
+}
+
+func command(pos parse.Pos, args ...parse.Node) *parse.CommandNode {
+	return &parse.CommandNode{NodeType: parse.NodeCommand, Pos: pos, Args: args}
+}
+
+func identifier(pos parse.Pos, ident string) *parse.IdentifierNode {
+	return &parse.IdentifierNode{NodeType: parse.NodeIdentifier, Pos: pos, Ident: ident}
+}